@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordActors registers the GORM plugin recording who made each write.
+func RecordActors(db *gorm.DB) error {
+	return db.Use(&actorPlugin{})
+}
+
+// actorFromContext returns the subject of the caller stored in ctx, which writes made with ctx
+// are recorded as made by.
+func actorFromContext(ctx context.Context) (string, bool) {
+	principal, ok := PrincipalFromContext(ctx)
+	return principal.Subject, ok && principal.Subject != ""
+}
+
+// actorPlugin is a GORM plugin setting the CreatedBy and UpdatedBy fields of models that have
+// them to the caller in the statement's context, including the UpdatedBy field of soft-deleted
+// rows. Statements without a caller, such as those run from the command line, leave the fields
+// as they are.
+type actorPlugin struct{}
+
+func (p *actorPlugin) Name() string {
+	return "actor"
+}
+
+func (p *actorPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("actor:before_create", p.before("CreatedBy", "UpdatedBy")); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("actor:before_update", p.before("UpdatedBy")); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("actor:before_delete", p.beforeDelete)
+}
+
+// beforeDelete adds UpdatedBy to the UPDATE a soft delete is turned into. The soft delete
+// clause builds that statement setting only the deletion time, so it is built here first and
+// then again with the actor added; gorm:delete runs the statement already built.
+func (p *actorPlugin) beforeDelete(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SkipHooks || stmt.Context == nil || stmt.Unscoped {
+		return
+	}
+	field := stmt.Schema.LookUpField("UpdatedBy")
+	if field == nil {
+		return
+	}
+	actor, ok := actorFromContext(stmt.Context)
+	if !ok {
+		return
+	}
+
+	for _, c := range stmt.Schema.DeleteClauses {
+		stmt.AddClause(c)
+	}
+	set, ok := stmt.Clauses["SET"]
+	if !ok || stmt.SQL.Len() == 0 {
+		// Not a soft delete, so there is no row left to record the actor on.
+		return
+	}
+	assignments, _ := set.Expression.(clause.Set)
+	set.Expression = append(assignments, clause.Assignment{Column: clause.Column{Name: field.DBName}, Value: actor})
+	stmt.Clauses["SET"] = set
+	stmt.SetColumn(field.DBName, actor, true)
+
+	stmt.SQL.Reset()
+	stmt.Vars = nil
+	stmt.Build(db.Callback().Update().Clauses...)
+}
+
+func (p *actorPlugin) before(fields ...string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		stmt := db.Statement
+		if db.Error != nil || stmt.Schema == nil || stmt.SkipHooks || stmt.Context == nil {
+			return
+		}
+		actor, ok := actorFromContext(stmt.Context)
+		if !ok {
+			return
+		}
+
+		for _, name := range fields {
+			field := stmt.Schema.LookUpField(name)
+			if field == nil {
+				continue
+			}
+			stmt.SetColumn(field.DBName, actor, true)
+			// Updates limited to some columns must include this one for it to be written.
+			if len(stmt.Selects) > 0 && !containsString(stmt.Selects, "*") && !containsString(stmt.Selects, field.DBName) {
+				stmt.Selects = append(stmt.Selects, field.DBName)
+			}
+		}
+	}
+}
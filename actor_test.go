@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestActorPlugin(t *testing.T) {
+	// A dry run builds each statement without a database, so the columns written can be checked.
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := RecordActors(db); err != nil {
+		t.Fatalf("failed to register plugin: %v", err)
+	}
+	ctx := context.WithValue(context.Background(), principalContextKey, Principal{Subject: "alice"})
+
+	product := Product{Name: "Laptop"}
+	db.WithContext(ctx).Omit(clause.Associations).Create(&product)
+	if product.CreatedBy != "alice" || product.UpdatedBy != "alice" {
+		t.Errorf("create should record the actor. got created by %q, updated by %q", product.CreatedBy, product.UpdatedBy)
+	}
+
+	product.ID, product.CreatedBy = 1, "bob"
+	db.WithContext(ctx).Omit(clause.Associations).Save(&product)
+	if product.CreatedBy != "bob" || product.UpdatedBy != "alice" {
+		t.Errorf("save should only record the updater. got created by %q, updated by %q", product.CreatedBy, product.UpdatedBy)
+	}
+
+	var tests = []struct {
+		name  string
+		query func(*gorm.DB) *gorm.DB
+		want  bool
+	}{
+		{"single column", func(tx *gorm.DB) *gorm.DB {
+			return tx.WithContext(ctx).Model(&Product{}).Where("id = ?", 1).Update("status", StatusArchived)
+		}, true},
+		{"selected columns", func(tx *gorm.DB) *gorm.DB {
+			return tx.WithContext(ctx).Model(&Product{ID: 1}).Select("price").Updates(Product{Price: 10})
+		}, true},
+		{"soft delete", func(tx *gorm.DB) *gorm.DB {
+			return tx.WithContext(ctx).Delete(&Product{}, 1)
+		}, true},
+		{"hard delete", func(tx *gorm.DB) *gorm.DB {
+			return tx.WithContext(ctx).Unscoped().Delete(&Product{}, 1)
+		}, false},
+		{"no caller", func(tx *gorm.DB) *gorm.DB {
+			return tx.Model(&Product{}).Where("id = ?", 1).Update("status", StatusArchived)
+		}, false},
+		{"model without actor fields", func(tx *gorm.DB) *gorm.DB {
+			return tx.WithContext(ctx).Model(&ProductMedia{}).Where("id = ?", 1).Update("position", 2)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt := tt.query(db).Statement
+			written := false
+			for _, v := range stmt.Vars {
+				if v == "alice" {
+					written = true
+				}
+			}
+			if written != tt.want {
+				t.Errorf("actor written incorrect. got %v, want %v: %s %v", written, tt.want, stmt.SQL.String(), stmt.Vars)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *APIKeyService
+	validator     *validator.Validate
+}
+
+func NewAPIKeyHandler(service *APIKeyService, validator *validator.Validate) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: service, validator: validator}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Create API key")
+
+	var request APIKeyCreateRequest
+	if !decodeAndValidate(w, r, h.validator, &request, "create API key") {
+		return
+	}
+
+	apiKey, err := h.apiKeyService.CreateAPIKey(r.Context(), request)
+	if err != nil {
+		handleAPIKeyError(w, r, "Failed to create API key", err)
+		return
+	}
+
+	logger.Info("API key created successfully", zap.Uint("API key ID", apiKey.ID), zap.Strings("scopes", apiKey.Scopes))
+	httpCreated(w, apiKey)
+}
+
+func (h *APIKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get API keys")
+
+	keys, err := h.apiKeyService.GetAPIKeys(r.Context())
+	if err != nil {
+		handleAPIKeyError(w, r, "Failed to get API keys", err)
+		return
+	}
+
+	httpOK(w, keys)
+}
+
+func (h *APIKeyHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Rotate API key", zap.String("path", r.URL.Path))
+
+	id, ok := parsePathID(w, r, "id", "invalid API key ID")
+	if !ok {
+		return
+	}
+
+	apiKey, err := h.apiKeyService.RotateAPIKey(r.Context(), id)
+	if err != nil {
+		handleAPIKeyError(w, r, "Failed to rotate API key", err)
+		return
+	}
+
+	logger.Info("API key rotated successfully", zap.Int("API key ID", id))
+	httpOK(w, apiKey)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Revoke API key", zap.String("path", r.URL.Path))
+
+	id, ok := parsePathID(w, r, "id", "invalid API key ID")
+	if !ok {
+		return
+	}
+
+	err := h.apiKeyService.RevokeAPIKey(r.Context(), id)
+	if err != nil {
+		handleAPIKeyError(w, r, "Failed to revoke API key", err)
+		return
+	}
+
+	logger.Info("API key revoked successfully", zap.Int("API key ID", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleAPIKeyError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	logger := LoggerFromContext(r.Context())
+	switch {
+	case errors.Is(err, ErrAPIKeyNotFound):
+		logger.Info(msg, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrAPIKeyRevoked):
+		logger.Info(msg, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error(msg, zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+	}
+}
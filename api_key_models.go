@@ -0,0 +1,42 @@
+package main
+
+import "time"
+
+const (
+	ScopeProductsRead  = "products:read"
+	ScopeProductsWrite = "products:write"
+	ScopeProductsAdmin = "products:admin"
+)
+
+// impliedScopes lists the scopes each scope grants: admin includes write, which includes read.
+var impliedScopes = map[string][]string{
+	ScopeProductsRead:  {ScopeProductsRead},
+	ScopeProductsWrite: {ScopeProductsRead, ScopeProductsWrite},
+	ScopeProductsAdmin: {ScopeProductsRead, ScopeProductsWrite, ScopeProductsAdmin},
+}
+
+// APIKey is a credential for the API. Only a SHA-256 hash of the key is stored; Prefix, which
+// is also part of the key, identifies the row to compare it against.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Name       string     `gorm:"type:text;not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(32);not null;uniqueIndex" json:"prefix"`
+	Hash       string     `gorm:"type:char(64);not null" json:"-"`
+	Scopes     StringList `gorm:"type:jsonb;not null;default:'[]'" json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type APIKeyCreateRequest struct {
+	Name   string   `json:"name" validate:"required,max=128"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=products:read products:write products:admin"`
+}
+
+// IssuedAPIKey is returned when a key is created or rotated. Key is the only time the secret is
+// shown; it can't be recovered later.
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyTag         = "sk_"
+	apiKeyPrefixBytes = 6
+	apiKeySecretBytes = 32
+)
+
+// lastUsedResolution limits how often a key's last use is written, so a busy key doesn't turn
+// every request into a write.
+const lastUsedResolution = time.Minute
+
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+// CreateAPIKey stores a new key and returns it along with its secret form.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req APIKeyCreateRequest) (*IssuedAPIKey, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.CreateAPIKey")
+	defer span.End()
+
+	prefix, key, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := APIKey{
+		Name:   req.Name,
+		Prefix: prefix,
+		Hash:   hashAPIKey(key),
+		Scopes: StringList(req.Scopes),
+	}
+
+	err = s.db.WithContext(ctx).Create(&apiKey).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssuedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+func (s *APIKeyService) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.GetAPIKeys")
+	defer span.End()
+
+	keys := []APIKey{}
+
+	err := s.db.WithContext(ctx).Order("id ASC").Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *APIKeyService) getAPIKey(ctx context.Context, id int) (*APIKey, error) {
+	var apiKey APIKey
+
+	err := s.db.WithContext(ctx).Where("id = ?", id).First(&apiKey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+// RotateAPIKey replaces a key's secret, keeping its name and scopes. The old secret stops
+// working immediately.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, id int) (*IssuedAPIKey, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.RotateAPIKey")
+	defer span.End()
+
+	apiKey, err := s.getAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	prefix, key, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey.Prefix = prefix
+	apiKey.Hash = hashAPIKey(key)
+	apiKey.LastUsedAt = nil
+
+	err = s.db.WithContext(ctx).Select("Prefix", "Hash", "LastUsedAt").Updates(apiKey).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssuedAPIKey{APIKey: *apiKey, Key: key}, nil
+}
+
+// RevokeAPIKey disables a key for good. Revoking a revoked key is not an error.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "APIKeyService.RevokeAPIKey")
+	defer span.End()
+
+	apiKey, err := s.getAPIKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Model(apiKey).Update("revoked_at", time.Now()).Error
+}
+
+// VerifyAPIKey returns the unrevoked key matching key, or ErrInvalidAPIKey, and records that
+// it was used.
+func (s *APIKeyService) VerifyAPIKey(ctx context.Context, key string) (*APIKey, error) {
+	ctx, span := tracer.Start(ctx, "APIKeyService.VerifyAPIKey")
+	defer span.End()
+
+	prefix, ok := parseAPIKey(key)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var apiKey APIKey
+	err := s.db.WithContext(ctx).Where("prefix = ? AND revoked_at IS NULL", prefix).First(&apiKey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(hashAPIKey(key))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution {
+		// A failure here shouldn't fail the request the key is being used for.
+		err := s.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", apiKey.ID).UpdateColumn("last_used_at", now).Error
+		if err != nil {
+			LoggerFromContext(ctx).Warn("Failed to record API key use", zap.Uint("API key ID", apiKey.ID), zap.Error(err))
+		} else {
+			apiKey.LastUsedAt = &now
+		}
+	}
+
+	return &apiKey, nil
+}
+
+// generateAPIKey returns a new key of the form sk_<prefix>_<secret> along with its prefix.
+func generateAPIKey() (prefix, key string, err error) {
+	b := make([]byte, apiKeyPrefixBytes+apiKeySecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	prefix = hex.EncodeToString(b[:apiKeyPrefixBytes])
+	return prefix, apiKeyTag + prefix + "_" + base64.RawURLEncoding.EncodeToString(b[apiKeyPrefixBytes:]), nil
+}
+
+// parseAPIKey returns the prefix of a key in the form made by generateAPIKey.
+func parseAPIKey(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyTag)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != hex.EncodedLen(apiKeyPrefixBytes) || secret == "" {
+		return "", false
+	}
+	if _, err := hex.DecodeString(prefix); err != nil {
+		return "", false
+	}
+	return prefix, true
+}
+
+// hashAPIKey returns the hex SHA-256 of key. Keys carry 256 random bits, so a fast hash is
+// enough; there is nothing to brute-force.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+func (h *ProductHandler) CreateAttributeDefinition(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Create attribute definition")
+
+	var request AttributeDefinitionCreateRequest
+	err := decodeJSON(r.Body, &request)
+	if err != nil {
+		writeBodyError(w, r, "create attribute definition", err)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			logger.Info("Failed to create attribute definition because request failed validation", zap.Any("validationErrors", validationErrors))
+			httpBadRequest(w, formatValidationErrors(validationErrors))
+			return
+		}
+		logger.Error("Unexpected error occurred during AttributeDefinitionCreateRequest validation", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	definition, err := h.productService.CreateAttributeDefinition(r.Context(), request)
+	if err != nil {
+		var attributeErrors AttributeErrors
+		if errors.As(err, &attributeErrors) {
+			logger.Info("Failed to create attribute definition because definition was invalid", zap.Error(err))
+			httpBadRequest(w, attributeErrors)
+			return
+		}
+		if errors.Is(err, ErrDuplicateAttribute) {
+			logger.Info("Failed to create attribute definition", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to create attribute definition", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Attribute definition created successfully", zap.Uint("definition ID", definition.ID))
+	httpCreated(w, definition)
+}
+
+func (h *ProductHandler) GetAttributeDefinitions(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get attribute definitions", zap.String("path", r.URL.Path))
+
+	definitions, err := h.productService.GetAttributeDefinitions(r.Context(), r.URL.Query().Get("category"))
+	if err != nil {
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to get attribute definitions", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	httpOK(w, definitions)
+}
+
+func (h *ProductHandler) DeleteAttributeDefinition(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Delete attribute definition", zap.String("path", r.URL.Path))
+
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		logger.Info("Failed to delete attribute definition because ID was invalid", zap.String("path", r.URL.Path))
+		http.Error(w, "invalid attribute definition ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.productService.DeleteAttributeDefinition(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrAttributeDefinitionNotFound) {
+			logger.Info("Failed to delete attribute definition because it was not found", zap.Int("definition ID", id))
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to delete attribute definition", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Attribute definition deleted successfully", zap.Int("definition ID", id))
+	w.WriteHeader(http.StatusNoContent)
+}
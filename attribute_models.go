@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeInteger = "integer"
+	AttributeTypeBoolean = "boolean"
+)
+
+var attributeNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// AttributeDefinition describes one custom attribute that products in a category may carry.
+type AttributeDefinition struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Category  string     `gorm:"type:text;not null;uniqueIndex:idx_attribute_category_name" json:"category"`
+	Name      string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_attribute_category_name" json:"name"`
+	Type      string     `gorm:"type:varchar(16);not null" json:"type"`
+	Required  bool       `gorm:"not null;default:false" json:"required"`
+	Enum      StringList `gorm:"type:jsonb;not null;default:'[]'" json:"enum,omitempty"`
+	Min       *float64   `json:"min,omitempty"`
+	Max       *float64   `json:"max,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type AttributeDefinitionCreateRequest struct {
+	Category string   `json:"category" validate:"required"`
+	Name     string   `json:"name" validate:"required,max=64"`
+	Type     string   `json:"type" validate:"required,oneof=string number integer boolean"`
+	Required bool     `json:"required,omitempty"`
+	Enum     []string `json:"enum,omitempty" validate:"omitempty,dive,required"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+}
+
+// AttributeErrors maps attribute paths to the reason they were rejected.
+type AttributeErrors map[string]string
+
+func (e AttributeErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %s", key, e[key]))
+	}
+	return "invalid attributes: " + strings.Join(parts, "; ")
+}
+
+// CheckDefinition reports problems with a definition that the struct tags can't express.
+func (req AttributeDefinitionCreateRequest) CheckDefinition() AttributeErrors {
+	errs := AttributeErrors{}
+	numeric := req.Type == AttributeTypeNumber || req.Type == AttributeTypeInteger
+
+	if !attributeNamePattern.MatchString(req.Name) {
+		errs["name"] = "name may only contain letters, digits, '_' and '-'"
+	}
+	if len(req.Enum) > 0 && req.Type != AttributeTypeString {
+		errs["enum"] = "enum is only supported for string attributes"
+	}
+	if (req.Min != nil || req.Max != nil) && !numeric {
+		errs["min"] = "range is only supported for number and integer attributes"
+	}
+	if req.Min != nil && req.Max != nil && *req.Min > *req.Max {
+		errs["max"] = "max must not be less than min"
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ValidateAttributes checks attrs against the definitions of a category. When a category
+// has no definitions its attributes are free-form; otherwise unknown keys are rejected.
+func ValidateAttributes(definitions []AttributeDefinition, attrs map[string]interface{}) AttributeErrors {
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	errs := AttributeErrors{}
+	known := make(map[string]bool, len(definitions))
+
+	for _, def := range definitions {
+		known[def.Name] = true
+		key := "attributes." + def.Name
+
+		value, ok := attrs[def.Name]
+		if !ok || value == nil {
+			if def.Required {
+				errs[key] = "attribute is required"
+			}
+			continue
+		}
+
+		if msg := checkAttributeValue(def, value); msg != "" {
+			errs[key] = msg
+		}
+	}
+
+	for name := range attrs {
+		if !known[name] {
+			errs["attributes."+name] = "attribute is not defined for this category"
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func checkAttributeValue(def AttributeDefinition, value interface{}) string {
+	switch def.Type {
+	case AttributeTypeString:
+		s, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if len(def.Enum) > 0 && !containsString(def.Enum, s) {
+			return fmt.Sprintf("must be one of [%s]", strings.Join(def.Enum, ", "))
+		}
+	case AttributeTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return "must be a boolean"
+		}
+	case AttributeTypeNumber, AttributeTypeInteger:
+		n, ok := value.(float64)
+		if !ok {
+			return "must be a number"
+		}
+		if def.Type == AttributeTypeInteger && n != math.Trunc(n) {
+			return "must be an integer"
+		}
+		if def.Min != nil && n < *def.Min {
+			return fmt.Sprintf("must be at least %v", *def.Min)
+		}
+		if def.Max != nil && n > *def.Max {
+			return fmt.Sprintf("must be at most %v", *def.Max)
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestValidateAttributes(t *testing.T) {
+	definitions := []AttributeDefinition{
+		{Name: "voltage", Type: AttributeTypeInteger, Required: true, Min: floatPtr(1), Max: floatPtr(240)},
+		{Name: "fabric", Type: AttributeTypeString, Enum: StringList{"cotton", "wool"}},
+		{Name: "weight", Type: AttributeTypeNumber},
+		{Name: "organic", Type: AttributeTypeBoolean},
+	}
+
+	var tests = []struct {
+		name        string
+		definitions []AttributeDefinition
+		attrs       map[string]interface{}
+		invalid     []string
+	}{
+		{"no definitions", nil, map[string]interface{}{"anything": "goes"}, nil},
+		{"valid attributes", definitions, map[string]interface{}{"voltage": float64(120), "fabric": "wool", "weight": 1.5, "organic": true}, nil},
+		{"missing required", definitions, map[string]interface{}{"fabric": "wool"}, []string{"attributes.voltage"}},
+		{"null required", definitions, map[string]interface{}{"voltage": nil}, []string{"attributes.voltage"}},
+		{"not an integer", definitions, map[string]interface{}{"voltage": 1.5}, []string{"attributes.voltage"}},
+		{"below range", definitions, map[string]interface{}{"voltage": float64(0)}, []string{"attributes.voltage"}},
+		{"above range", definitions, map[string]interface{}{"voltage": float64(241)}, []string{"attributes.voltage"}},
+		{"not in enum", definitions, map[string]interface{}{"voltage": float64(5), "fabric": "silk"}, []string{"attributes.fabric"}},
+		{"wrong types", definitions, map[string]interface{}{"voltage": "5", "weight": "heavy", "organic": "yes"}, []string{"attributes.voltage", "attributes.weight", "attributes.organic"}},
+		{"unknown attribute", definitions, map[string]interface{}{"voltage": float64(5), "isbn": "123"}, []string{"attributes.isbn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateAttributes(tt.definitions, tt.attrs)
+			if len(errs) != len(tt.invalid) {
+				t.Fatalf("error count incorrect. got %v, want errors for %v", errs, tt.invalid)
+			}
+			for _, key := range tt.invalid {
+				if _, ok := errs[key]; !ok {
+					t.Errorf("missing error for %s. got %v", key, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestAttributeFilterDocuments(t *testing.T) {
+	var tests = []struct {
+		name  string
+		value string
+		typed string
+		raw   string
+	}{
+		{"string", "wool", `{"fabric":"wool"}`, `{"fabric":"wool"}`},
+		{"number", "120", `{"fabric":120}`, `{"fabric":"120"}`},
+		{"boolean", "true", `{"fabric":true}`, `{"fabric":"true"}`},
+		{"quoted", `"wool"`, `{"fabric":"\"wool\""}`, `{"fabric":"\"wool\""}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typed, raw := attributeFilterDocuments("fabric", tt.value)
+			if typed != tt.typed {
+				t.Errorf("typed document incorrect. got %s, want %s", typed, tt.typed)
+			}
+			if raw != tt.raw {
+				t.Errorf("raw document incorrect. got %s, want %s", raw, tt.raw)
+			}
+		})
+	}
+}
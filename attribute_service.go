@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+func (s *ProductService) CreateAttributeDefinition(ctx context.Context, req AttributeDefinitionCreateRequest) (*AttributeDefinition, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.CreateAttributeDefinition")
+	defer span.End()
+
+	if errs := req.CheckDefinition(); errs != nil {
+		return nil, errs
+	}
+
+	definition := AttributeDefinition{
+		Category: req.Category,
+		Name:     req.Name,
+		Type:     req.Type,
+		Required: req.Required,
+		Enum:     StringList(req.Enum),
+		Min:      req.Min,
+		Max:      req.Max,
+	}
+
+	err := s.db.WithContext(ctx).Create(&definition).Error
+	if err != nil {
+		if isUniqueConstraintError(err) {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateAttribute, req.Name)
+		}
+		return nil, err
+	}
+
+	return &definition, nil
+}
+
+func (s *ProductService) GetAttributeDefinitions(ctx context.Context, category string) ([]AttributeDefinition, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetAttributeDefinitions")
+	defer span.End()
+
+	definitions := []AttributeDefinition{}
+
+	query := s.db.WithContext(ctx).Order("category ASC, name ASC")
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	err := query.Find(&definitions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return definitions, nil
+}
+
+func (s *ProductService) DeleteAttributeDefinition(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "ProductService.DeleteAttributeDefinition")
+	defer span.End()
+
+	result := s.db.WithContext(ctx).Delete(&AttributeDefinition{}, id)
+
+	if result.RowsAffected == 0 {
+		return ErrAttributeDefinitionNotFound
+	}
+
+	return result.Error
+}
+
+// validateAttributes checks attrs against the attribute definitions of category, reading them
+// through db so that it can run inside a transaction.
+func validateAttributes(db *gorm.DB, category string, attrs JSONMap) error {
+	var definitions []AttributeDefinition
+
+	err := db.Where("category = ?", category).Find(&definitions).Error
+	if err != nil {
+		return err
+	}
+
+	if errs := ValidateAttributes(definitions, attrs); errs != nil {
+		return errs
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestProductAttributes(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	definitions := []AttributeDefinitionCreateRequest{
+		{Category: "electronics", Name: "voltage", Type: AttributeTypeInteger, Required: true, Min: floatPtr(1), Max: floatPtr(240)},
+		{Category: "clothing", Name: "fabric", Type: AttributeTypeString, Enum: []string{"cotton", "wool"}},
+	}
+	for _, definition := range definitions {
+		e.POST("/api/v1/attribute-definitions").WithJSON(definition).
+			Expect().
+			Status(http.StatusCreated)
+	}
+
+	// defining the same attribute twice should conflict
+	e.POST("/api/v1/attribute-definitions").WithJSON(definitions[0]).
+		Expect().
+		Status(http.StatusConflict)
+
+	// enum on a numeric attribute is not allowed
+	e.POST("/api/v1/attribute-definitions").WithJSON(AttributeDefinitionCreateRequest{
+		Category: "electronics", Name: "watts", Type: AttributeTypeNumber, Enum: []string{"10"},
+	}).Expect().Status(http.StatusBadRequest)
+
+	e.GET("/api/v1/attribute-definitions").WithQuery("category", "electronics").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+
+	testCases := []struct {
+		name           string
+		product        ProductCreateRequest
+		expectedStatus int
+	}{
+		{
+			name:           "Valid attributes",
+			product:        ProductCreateRequest{Name: "kettle", SKU: "a1", Price: 20, Category: "electronics", Attributes: map[string]interface{}{"voltage": 230}},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Missing required attribute",
+			product:        ProductCreateRequest{Name: "toaster", SKU: "a2", Price: 20, Category: "electronics"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Attribute out of range",
+			product:        ProductCreateRequest{Name: "heater", SKU: "a3", Price: 20, Category: "electronics", Attributes: map[string]interface{}{"voltage": 400}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Attribute not in enum",
+			product:        ProductCreateRequest{Name: "scarf", SKU: "a4", Price: 20, Category: "clothing", Attributes: map[string]interface{}{"fabric": "silk"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Valid enum attribute",
+			product:        ProductCreateRequest{Name: "sweater", SKU: "a5", Price: 20, Category: "clothing", Attributes: map[string]interface{}{"fabric": "wool"}},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Free-form attributes in undefined category",
+			product:        ProductCreateRequest{Name: "novel", SKU: "a6", Price: 20, Category: "books", Attributes: map[string]interface{}{"isbn": "9780000000000"}},
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.POST("/api/v1/products").WithJSON(tc.product).
+				Expect().
+				Status(tc.expectedStatus)
+		})
+	}
+
+	// filtering on attribute values
+	body := e.GET("/api/v1/products").WithQuery("attr.fabric", "wool").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	body.Value("total_count").Number().IsEqual(1)
+	body.Value("products").Array().Value(0).Object().Value("name").String().IsEqual("sweater")
+
+	e.GET("/api/v1/products").WithQuery("attr.voltage", "230").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("total_count").Number().IsEqual(1)
+
+	e.GET("/api/v1/products").WithQuery("attr.bad name", "x").
+		Expect().
+		Status(http.StatusBadRequest)
+
+	// updates are validated against the attribute definitions
+	kettleID := int(e.GET("/api/v1/products").WithQuery("attr.voltage", "230").
+		Expect().
+		JSON().Object().Value("products").Array().Value(0).Object().Value("id").Number().Raw())
+
+	e.PATCH("/api/v1/products/" + strconv.Itoa(kettleID)).
+		WithJSON(ProductUpdateRequest{Attributes: map[string]interface{}{"voltage": 0}}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	e.PATCH("/api/v1/products/" + strconv.Itoa(kettleID)).
+		WithJSON(ProductUpdateRequest{Attributes: map[string]interface{}{"voltage": 110}}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("attributes").Object().Value("voltage").Number().IsEqual(110)
+}
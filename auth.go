@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// Principal is the caller a request was authenticated as.
+type Principal struct {
+	// Subject identifies the caller in logs and as the actor of writes, e.g. "api-key:3" or
+	// the subject of an SSO token.
+	Subject string
+	// Roles are the policy roles of an SSO token; API keys are granted scopes directly.
+	Roles  []string
+	Scopes []string
+}
+
+// HasScope reports whether p was granted scope, directly or through a broader scope.
+func (p Principal) HasScope(scope string) bool {
+	for _, granted := range p.Scopes {
+		if containsString(impliedScopes[granted], scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// anonymousPrincipal is used for every request when authentication is disabled.
+var anonymousPrincipal = Principal{Subject: "anonymous", Scopes: []string{ScopeProductsAdmin}}
+
+// PrincipalFromContext returns the caller stored by Authenticator.Middleware.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey).(Principal)
+	return principal, ok
+}
+
+// Authenticator identifies the caller of each API request from an API key sent in an
+// "Authorization: Bearer" or X-API-Key header, or from an SSO token sent as a bearer token.
+type Authenticator struct {
+	enabled bool
+	keys    *APIKeyService
+	tokens  *TokenVerifier
+}
+
+// NewAuthenticator returns an Authenticator checking API keys with keys and, unless tokens is
+// nil, SSO tokens with tokens.
+func NewAuthenticator(cfg AuthConfig, keys *APIKeyService, tokens *TokenVerifier) *Authenticator {
+	return &Authenticator{enabled: cfg.Enabled, keys: keys, tokens: tokens}
+}
+
+// Middleware stores the caller's Principal in the request context, answering 401 when the
+// request carries no valid credentials. Which scopes a route needs is checked by requireScope.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey, anonymousPrincipal)))
+			return
+		}
+
+		logger := LoggerFromContext(r.Context())
+		credential := credentialFromRequest(r)
+		if credential == "" {
+			logger.Info("Rejected request without credentials")
+			unauthorized(w, "API key or token required")
+			return
+		}
+
+		principal, err := a.authenticate(r.Context(), credential)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrInvalidAPIKey):
+				logger.Info("Rejected request with an invalid API key")
+				unauthorized(w, ErrInvalidAPIKey.Error())
+			case errors.Is(err, ErrInvalidToken):
+				logger.Info("Rejected request with an invalid token", zap.Error(err))
+				w.Header().Set("WWW-Authenticate", `Bearer realm="simpler-test", error="invalid_token"`)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+			case errors.Is(err, ErrJWKSUnavailable):
+				logger.Error("Failed to verify token", zap.Error(err))
+				http.Error(w, ErrJWKSUnavailable.Error(), http.StatusServiceUnavailable)
+			default:
+				if writeContextError(w, r, err) {
+					return
+				}
+				logger.Error("Failed to verify API key", zap.Error(err))
+				http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), principalContextKey, principal)
+		ctx = WithLogger(ctx, logger.With(zap.String("principal", principal.Subject)))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticate returns the caller a credential belongs to. Credentials in the form of an API
+// key are checked as one; anything else is an SSO token when those are accepted.
+func (a *Authenticator) authenticate(ctx context.Context, credential string) (Principal, error) {
+	if a.tokens != nil && !strings.HasPrefix(credential, apiKeyTag) {
+		return a.tokens.Verify(ctx, credential)
+	}
+
+	apiKey, err := a.keys.VerifyAPIKey(ctx, credential)
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: "api-key:" + strconv.FormatUint(uint64(apiKey.ID), 10), Scopes: apiKey.Scopes}, nil
+}
+
+// credentialFromRequest returns the API key or token from the Authorization or X-API-Key header.
+func credentialFromRequest(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return strings.TrimSpace(r.Header.Get(APIKeyHeader))
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="simpler-test"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
+
+// requireScope wraps h so that it only runs for callers granted scope, answering 403 otherwise.
+func requireScope(scope string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := PrincipalFromContext(r.Context())
+		if !ok || !principal.HasScope(scope) {
+			LoggerFromContext(r.Context()).Info("Rejected request lacking a scope", zap.String("scope", scope))
+			http.Error(w, "missing required scope "+scope, http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
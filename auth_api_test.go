@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	router, logger, db := initRouterWith(func(cfg *Config) { cfg.Auth.Enabled = true })
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	bootstrap, err := NewAPIKeyService(db).CreateAPIKey(context.Background(), APIKeyCreateRequest{Name: "bootstrap", Scopes: []string{ScopeProductsAdmin}})
+	if err != nil {
+		t.Fatalf("failed to create API key: %v", err)
+	}
+	admin := e.Builder(func(req *httpexpect.Request) { req.WithHeader("Authorization", "Bearer "+bootstrap.Key) })
+
+	t.Run("Credentials are required", func(t *testing.T) {
+		e.GET("/api/v1/products").Expect().Status(http.StatusUnauthorized).Header("WWW-Authenticate").NotEmpty()
+		e.GET("/api/v1/products").WithHeader(APIKeyHeader, "sk_0123456789ab_wrong").Expect().Status(http.StatusUnauthorized)
+		e.GET("/api/v1/products").WithHeader("Authorization", "Bearer not-a-key").Expect().Status(http.StatusUnauthorized)
+		e.GET("/health/live").Expect().Status(http.StatusOK)
+	})
+
+	var readerKey string
+	var readerID float64
+	t.Run("Create a key", func(t *testing.T) {
+		created := admin.POST("/api/v1/api-keys").WithJSON(APIKeyCreateRequest{Name: "storefront", Scopes: []string{ScopeProductsRead}}).
+			Expect().Status(http.StatusCreated).JSON().Object()
+		created.Value("scopes").Array().IsEqual([]string{ScopeProductsRead})
+		created.NotContainsKey("hash")
+		readerKey = created.Value("key").String().NotEmpty().Raw()
+		readerID = created.Value("id").Number().Raw()
+
+		admin.POST("/api/v1/api-keys").WithJSON(APIKeyCreateRequest{Name: "bad", Scopes: []string{"everything"}}).
+			Expect().Status(http.StatusBadRequest)
+	})
+
+	t.Run("Scopes are enforced", func(t *testing.T) {
+		reader := e.Builder(func(req *httpexpect.Request) { req.WithHeader(APIKeyHeader, readerKey) })
+		reader.GET("/api/v1/products").Expect().Status(http.StatusOK)
+		reader.POST("/api/v1/products/lookup").WithJSON(ProductLookupRequest{SKUs: []string{"1234"}}).Expect().Status(http.StatusOK)
+		reader.POST("/api/v1/products").WithJSON(getSampleProductRequests()[0]).Expect().Status(http.StatusForbidden)
+		reader.GET("/api/v1/api-keys").Expect().Status(http.StatusForbidden)
+
+		admin.POST("/api/v1/products").WithJSON(getSampleProductRequests()[0]).Expect().Status(http.StatusCreated)
+		admin.DELETE("/api/v1/products/1").Expect().Status(http.StatusNoContent)
+	})
+
+	t.Run("Last use is recorded", func(t *testing.T) {
+		keys := admin.GET("/api/v1/api-keys").Expect().Status(http.StatusOK).JSON().Array()
+		keys.Length().IsEqual(2)
+		keys.Value(1).Object().Value("last_used_at").String().NotEmpty()
+	})
+
+	t.Run("Rotate a key", func(t *testing.T) {
+		rotated := admin.POST("/api/v1/api-keys/{id}/rotate", readerID).Expect().Status(http.StatusOK).JSON().Object()
+		rotated.Value("id").Number().IsEqual(readerID)
+		newKey := rotated.Value("key").String().NotEqual(readerKey).Raw()
+
+		e.GET("/api/v1/products").WithHeader(APIKeyHeader, readerKey).Expect().Status(http.StatusUnauthorized)
+		e.GET("/api/v1/products").WithHeader(APIKeyHeader, newKey).Expect().Status(http.StatusOK)
+		readerKey = newKey
+	})
+
+	t.Run("Revoke a key", func(t *testing.T) {
+		admin.DELETE("/api/v1/api-keys/{id}", readerID).Expect().Status(http.StatusNoContent)
+		e.GET("/api/v1/products").WithHeader(APIKeyHeader, readerKey).Expect().Status(http.StatusUnauthorized)
+		admin.POST("/api/v1/api-keys/{id}/rotate", readerID).Expect().Status(http.StatusConflict)
+		admin.DELETE("/api/v1/api-keys/1000000").Expect().Status(http.StatusNotFound)
+	})
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	rsaKey, _ := newSigningKeys(t)
+	jwksFile := writeJWKSFile(t, rsaKey)
+	router, logger, db := initRouterWith(func(cfg *Config) {
+		cfg.Auth = testAuthConfig(jwksFile)
+		cfg.Auth.Policy["auditor"] = []string{ScopeProductsRead}
+	})
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+	withToken := func(subject string, roles ...string) *httpexpect.Expect {
+		claims := validClaims()
+		claims["sub"] = subject
+		claims["roles"] = roles
+		token := signToken(t, rsaKey, claims)
+		return e.Builder(func(req *httpexpect.Request) { req.WithHeader("Authorization", "Bearer "+token) })
+	}
+	editor := withToken("alice@example.com", "editor")
+
+	t.Run("Invalid tokens are rejected", func(t *testing.T) {
+		expired := validClaims()
+		expired["exp"] = time.Now().Add(-time.Hour).Unix()
+		e.GET("/api/v1/products").WithHeader("Authorization", "Bearer "+signToken(t, rsaKey, expired)).
+			Expect().Status(http.StatusUnauthorized).Header("WWW-Authenticate").Contains("invalid_token")
+	})
+
+	t.Run("The policy is enforced", func(t *testing.T) {
+		withToken("carol@example.com", "auditor").GET("/api/v1/products").Expect().Status(http.StatusOK)
+		withToken("carol@example.com", "auditor").POST("/api/v1/products").WithJSON(getSampleProductRequests()[0]).Expect().Status(http.StatusForbidden)
+		withToken("dave@example.com").GET("/api/v1/products").Expect().Status(http.StatusForbidden)
+		editor.DELETE("/api/v1/products/1").Expect().Status(http.StatusForbidden)
+	})
+
+	t.Run("The subject is recorded as the actor", func(t *testing.T) {
+		created := editor.POST("/api/v1/products").WithJSON(getSampleProductRequests()[0]).Expect().Status(http.StatusCreated).JSON().Object()
+		created.Value("created_by").IsEqual("alice@example.com")
+		created.Value("updated_by").IsEqual("alice@example.com")
+		id := created.Value("id").Number().Raw()
+
+		update := getSampleProductRequests()[1]
+		updated := withToken("bob@example.com", "editor").PUT("/api/v1/products/{id}", id).WithJSON(update).
+			Expect().Status(http.StatusOK).JSON().Object()
+		updated.Value("created_by").IsEqual("alice@example.com")
+		updated.Value("updated_by").IsEqual("bob@example.com")
+
+		upserted := withToken("erin@example.com", "editor").PUT("/api/v1/products/sku/{sku}", "TOKEN-1").WithJSON(update).
+			Expect().Status(http.StatusCreated).JSON().Object()
+		upserted.Value("created_by").IsEqual("erin@example.com")
+
+		// deleted products keep a record of who deleted them
+		withToken("frank@example.com", "admin").DELETE("/api/v1/products/{id}", id).Expect().Status(http.StatusNoContent)
+		var deleted Product
+		if err := db.Unscoped().First(&deleted, int(id)).Error; err != nil {
+			t.Fatalf("failed to load deleted product: %v", err)
+		}
+		if deleted.UpdatedBy != "frank@example.com" || !deleted.DeletedAt.Valid {
+			t.Errorf("delete should record the actor. got updated by %q, deleted at %v", deleted.UpdatedBy, deleted.DeletedAt)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	prefix, key, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, ok := parseAPIKey(key)
+	if !ok || parsed != prefix {
+		t.Errorf("generated key %q did not parse to its prefix %q. got %q", key, prefix, parsed)
+	}
+	if _, other, _ := generateAPIKey(); other == key {
+		t.Errorf("expected distinct keys")
+	}
+	if hash := hashAPIKey(key); len(hash) != 64 || strings.Contains(hash, prefix) {
+		t.Errorf("hash incorrect. got %q", hash)
+	}
+}
+
+func TestParseAPIKey(t *testing.T) {
+	var tests = []struct {
+		name string
+		key  string
+		ok   bool
+	}{
+		{"valid", "sk_0123456789ab_c2VjcmV0", true},
+		{"secret with underscores", "sk_0123456789ab_c2Vj_cmV0", true},
+		{"missing tag", "0123456789ab_c2VjcmV0", false},
+		{"short prefix", "sk_0123_c2VjcmV0", false},
+		{"prefix not hex", "sk_0123456789xy_c2VjcmV0", false},
+		{"missing secret", "sk_0123456789ab_", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := parseAPIKey(tt.key); ok != tt.ok {
+				t.Errorf("parseAPIKey(%q) incorrect. got %v, want %v", tt.key, ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestPrincipalHasScope(t *testing.T) {
+	var tests = []struct {
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{[]string{ScopeProductsRead}, ScopeProductsRead, true},
+		{[]string{ScopeProductsRead}, ScopeProductsWrite, false},
+		{[]string{ScopeProductsWrite}, ScopeProductsRead, true},
+		{[]string{ScopeProductsWrite}, ScopeProductsAdmin, false},
+		{[]string{ScopeProductsAdmin}, ScopeProductsWrite, true},
+		{[]string{ScopeProductsRead, ScopeProductsAdmin}, ScopeProductsAdmin, true},
+		{[]string{"suppliers:write"}, ScopeProductsRead, false},
+		{nil, ScopeProductsRead, false},
+	}
+	for _, tt := range tests {
+		if got := (Principal{Scopes: tt.scopes}).HasScope(tt.scope); got != tt.want {
+			t.Errorf("%v HasScope(%q) incorrect. got %v, want %v", tt.scopes, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestCredentialFromRequest(t *testing.T) {
+	var tests = []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"bearer", map[string]string{"Authorization": "Bearer sk_key"}, "sk_key"},
+		{"lowercase scheme", map[string]string{"Authorization": "bearer sk_key"}, "sk_key"},
+		{"api key header", map[string]string{APIKeyHeader: "sk_key"}, "sk_key"},
+		{"bearer wins", map[string]string{"Authorization": "Bearer sk_one", APIKeyHeader: "sk_two"}, "sk_one"},
+		{"basic is ignored", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, ""},
+		{"none", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				request.Header.Set(name, value)
+			}
+			if got := credentialFromRequest(request); got != tt.want {
+				t.Errorf("credential incorrect. got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	handler := NewAuthenticator(AuthConfig{Enabled: false}, nil, nil).Middleware(
+		requireScope(ScopeProductsAdmin, func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := PrincipalFromContext(r.Context())
+			w.Write([]byte(principal.Subject))
+		}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/", nil))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "anonymous" {
+		t.Errorf("disabled authentication should allow everything. got %d %q", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	requireScope(ScopeProductsRead, func(http.ResponseWriter, *http.Request) {}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("requests without a principal should be forbidden. got %d", recorder.Code)
+	}
+}
+
+func TestAuthenticatorRequiresCredentials(t *testing.T) {
+	handler := NewAuthenticator(AuthConfig{Enabled: true}, nil, nil).Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Errorf("handler should not run")
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/products", nil))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status incorrect. got %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	if challenge := recorder.Header().Get("WWW-Authenticate"); !strings.HasPrefix(challenge, "Bearer") {
+		t.Errorf("challenge incorrect. got %q", challenge)
+	}
+}
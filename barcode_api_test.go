@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestProductBarcodes(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	requests := getSampleProductRequests()
+	requests[0].Barcode = "036000291452"
+	requests[1].Barcode = "4006381333931"
+
+	testCases := []struct {
+		name           string
+		barcode        string
+		sku            string
+		expectedStatus int
+	}{
+		{"Bad check digit", "4006381333932", "bad-check", http.StatusBadRequest},
+		{"Wrong length", "12345678905", "bad-length", http.StatusBadRequest},
+		{"Duplicate GTIN-13", "4006381333931", "dup-13", http.StatusConflict},
+		{"UPC-A duplicating its GTIN-13 form", "0036000291452", "dup-upc", http.StatusConflict},
+		{"Valid GTIN-8", "96385074", "gtin-8", http.StatusCreated},
+	}
+
+	// the UPC-A code is stored in its GTIN-13 form
+	e.POST("/api/v1/products").WithJSON(requests[0]).
+		Expect().
+		Status(http.StatusCreated).
+		JSON().Object().Value("barcode").String().IsEqual("0036000291452")
+	e.POST("/api/v1/products").WithJSON(requests[1]).
+		Expect().
+		Status(http.StatusCreated)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := getSampleProductRequests()[2]
+			req.SKU = tc.sku
+			req.Barcode = tc.barcode
+			e.POST("/api/v1/products").WithJSON(req).
+				Expect().
+				Status(tc.expectedStatus)
+		})
+	}
+
+	// products without a barcode don't conflict with each other
+	e.POST("/api/v1/products").WithJSON(requests[2]).Expect().Status(http.StatusCreated)
+	e.PATCH("/api/v1/products/1").WithJSON(ProductUpdateRequest{Barcode: strPtr("4006381333931")}).
+		Expect().
+		Status(http.StatusConflict)
+
+	// lookup accepts both the UPC-A and the GTIN-13 form
+	e.GET("/api/v1/products/by-barcode/036000291452").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("id").Number().IsEqual(1)
+	e.GET("/api/v1/products/by-barcode/0036000291452").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("id").Number().IsEqual(1)
+	e.GET("/api/v1/products/by-barcode/4006381333932").Expect().Status(http.StatusBadRequest)
+	e.GET("/api/v1/products/by-barcode/5901234123457").Expect().Status(http.StatusNotFound)
+
+	// a deleted product's barcode can no longer be found and may be reused
+	e.DELETE("/api/v1/products/2").Expect().Status(http.StatusNoContent)
+	e.GET("/api/v1/products/by-barcode/4006381333931").Expect().Status(http.StatusNotFound)
+	e.PATCH("/api/v1/products/1").WithJSON(ProductUpdateRequest{Barcode: strPtr("4006381333931")}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("barcode").String().IsEqual("4006381333931")
+
+	// clearing the barcode
+	e.PATCH("/api/v1/products/1").WithJSON(ProductUpdateRequest{Barcode: strPtr("")}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("barcode").String().IsEqual("")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	if err := store.Put("products/1/image.png", strings.NewReader("data")); err != nil {
+		t.Fatalf("put failed: %v", err)
+	}
+
+	reader, err := store.Get("products/1/image.png")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "data" {
+		t.Errorf("data incorrect. got %q, want %q", data, "data")
+	}
+
+	if err := store.Delete("products/1/image.png"); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := store.Get("products/1/image.png"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("get after delete returned %v, want ErrBlobNotFound", err)
+	}
+
+	// deleting a missing blob is not an error
+	if err := store.Delete("products/1/image.png"); err != nil {
+		t.Errorf("second delete failed: %v", err)
+	}
+
+	for _, key := range []string{"", "../escape", "products/../../escape"} {
+		if err := store.Put(key, strings.NewReader("data")); err == nil {
+			t.Errorf("put with key %q should have failed", key)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	ProductTypeSimple = "simple"
+	ProductTypeBundle = "bundle"
+
+	PricingFixed    = "fixed"
+	PricingComputed = "computed"
+
+	// MaxBundleDepth bounds how deeply bundles may be nested inside other bundles.
+	MaxBundleDepth = 5
+)
+
+// BundleComponent is one line of a bundle: Quantity units of the component product.
+type BundleComponent struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	BundleID    uint      `gorm:"not null;uniqueIndex:idx_bundle_component" json:"-"`
+	ComponentID uint      `gorm:"not null;uniqueIndex:idx_bundle_component;index" json:"component_id"`
+	Component   *Product  `gorm:"foreignKey:ComponentID" json:"-"`
+	Quantity    int       `gorm:"not null" json:"quantity"`
+	CreatedAt   time.Time `json:"-"`
+}
+
+type BundleComponentRequest struct {
+	ComponentID uint `json:"component_id" validate:"required"`
+	Quantity    int  `json:"quantity" validate:"required,min=1"`
+}
+
+// BundleAvailability derives a bundle's available quantity and component price from its
+// components, which must have their Component loaded. A bundle is only as available as its
+// scarcest component; a component that is missing (e.g. deleted) makes it unavailable.
+func BundleAvailability(components []BundleComponent) (quantity int, price float64) {
+	if len(components) == 0 {
+		return 0, 0
+	}
+
+	quantity = math.MaxInt
+	for _, c := range components {
+		if c.Component == nil || c.Quantity <= 0 {
+			quantity = 0
+			continue
+		}
+		quantity = min(quantity, c.Component.Quantity/c.Quantity)
+		price += c.Component.Price * float64(c.Quantity)
+	}
+
+	return quantity, math.Round(price*100) / 100
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestBundleAvailability(t *testing.T) {
+	cable := &Product{Price: 2.50, Quantity: 10}
+	charger := &Product{Price: 15.99, Quantity: 3}
+
+	var tests = []struct {
+		name       string
+		components []BundleComponent
+		quantity   int
+		price      float64
+	}{
+		{"no components", nil, 0, 0},
+		{"single component", []BundleComponent{{Component: cable, Quantity: 3}}, 3, 7.50},
+		{"scarcest component wins", []BundleComponent{{Component: cable, Quantity: 2}, {Component: charger, Quantity: 1}}, 3, 20.99},
+		{"not enough for one", []BundleComponent{{Component: charger, Quantity: 4}}, 0, 63.96},
+		{"missing component", []BundleComponent{{Component: cable, Quantity: 1}, {Component: nil, Quantity: 1}}, 0, 2.50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quantity, price := BundleAvailability(tt.components)
+			if quantity != tt.quantity {
+				t.Errorf("quantity incorrect. got %d, want %d", quantity, tt.quantity)
+			}
+			if price != tt.price {
+				t.Errorf("price incorrect. got %v, want %v", price, tt.price)
+			}
+		})
+	}
+}
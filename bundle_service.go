@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// checkBundleFields rejects bundle-only settings on simple products.
+func checkBundleFields(product *Product, hasComponents bool) error {
+	if product.Type == ProductTypeBundle {
+		return nil
+	}
+	if hasComponents {
+		return fmt.Errorf("%w: only bundles can have components", ErrInvalidBundle)
+	}
+	if product.Pricing == PricingComputed {
+		return fmt.Errorf("%w: only bundles can have computed pricing", ErrInvalidBundle)
+	}
+	return nil
+}
+
+// replaceBundleComponents validates reqs and stores them as the components of bundle,
+// replacing any existing ones. Components must be live products, may not repeat, and may not
+// contain the bundle itself at any depth.
+func replaceBundleComponents(tx *gorm.DB, bundle *Product, reqs []BundleComponentRequest) error {
+	if len(reqs) == 0 {
+		return fmt.Errorf("%w: a bundle needs at least one component", ErrInvalidBundle)
+	}
+
+	components := make([]BundleComponent, 0, len(reqs))
+	seen := make(map[uint]bool, len(reqs))
+
+	for _, req := range reqs {
+		if seen[req.ComponentID] {
+			return fmt.Errorf("%w: component %d is listed more than once", ErrInvalidBundle, req.ComponentID)
+		}
+		seen[req.ComponentID] = true
+
+		if req.ComponentID == bundle.ID {
+			return fmt.Errorf("%w: a bundle can't contain itself", ErrInvalidBundle)
+		}
+
+		// The share lock holds off DeleteProduct until this transaction is done with the component.
+		var found []uint
+		err := tx.Model(&Product{}).Clauses(clause.Locking{Strength: "SHARE"}).
+			Where("id = ?", req.ComponentID).Pluck("id", &found).Error
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("%w: component %d does not exist or has been deleted", ErrInvalidBundle, req.ComponentID)
+		}
+
+		contains, err := bundleContains(tx, req.ComponentID, bundle.ID, 1)
+		if err != nil {
+			return err
+		}
+		if contains {
+			return fmt.Errorf("%w: component %d contains this bundle", ErrInvalidBundle, req.ComponentID)
+		}
+
+		components = append(components, BundleComponent{
+			BundleID:    bundle.ID,
+			ComponentID: req.ComponentID,
+			Quantity:    req.Quantity,
+		})
+	}
+
+	if err := tx.Where("bundle_id = ?", bundle.ID).Delete(&BundleComponent{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&components).Error; err != nil {
+		return err
+	}
+
+	if bundle.Pricing == PricingComputed {
+		return storeComputedPrice(tx, bundle)
+	}
+	return nil
+}
+
+// bundleContains reports whether target appears in the component tree of the product id.
+// Trees deeper than MaxBundleDepth are rejected.
+func bundleContains(tx *gorm.DB, id, target uint, depth int) (bool, error) {
+	var componentIDs []uint
+	err := tx.Model(&BundleComponent{}).Where("bundle_id = ?", id).Pluck("component_id", &componentIDs).Error
+	if err != nil {
+		return false, err
+	}
+	if len(componentIDs) > 0 && depth >= MaxBundleDepth {
+		return false, fmt.Errorf("%w: bundles can be nested at most %d levels deep", ErrInvalidBundle, MaxBundleDepth)
+	}
+
+	for _, componentID := range componentIDs {
+		if componentID == target {
+			return true, nil
+		}
+		contains, err := bundleContains(tx, componentID, target, depth+1)
+		if err != nil || contains {
+			return contains, err
+		}
+	}
+	return false, nil
+}
+
+// storeComputedPrice saves the price derived from the bundle's components in its price column,
+// which filters and stats read. It runs when the bundle itself is written; refreshBundlePrices
+// keeps the column current when one of its components changes.
+func storeComputedPrice(tx *gorm.DB, bundle *Product) error {
+	if err := resolveBundle(tx, bundle, 0); err != nil {
+		return err
+	}
+	return tx.Model(&Product{}).Where("id = ?", bundle.ID).Update("price", bundle.Price).Error
+}
+
+// refreshBundlePrices stores the price again for every bundle with computed pricing that
+// contains the product, directly or through other such bundles. It must run in the
+// transaction that writes the product, after the write.
+func refreshBundlePrices(tx *gorm.DB, id uint) error {
+	return refreshBundlePricesAt(tx, id, 1)
+}
+
+func refreshBundlePricesAt(tx *gorm.DB, id uint, depth int) error {
+	var bundles []Product
+	err := tx.Where("pricing = ? AND id IN (?)", PricingComputed,
+		tx.Model(&BundleComponent{}).Select("bundle_id").Where("component_id = ?", id)).
+		Find(&bundles).Error
+	if err != nil {
+		return err
+	}
+	if len(bundles) > 0 && depth > MaxBundleDepth {
+		return fmt.Errorf("%w: bundle %d is nested too deeply", ErrInvalidBundle, bundles[0].ID)
+	}
+
+	for i := range bundles {
+		if err := storeComputedPrice(tx, &bundles[i]); err != nil {
+			return err
+		}
+		if err := refreshBundlePricesAt(tx, bundles[i].ID, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveBundle loads the components of a bundle and derives its available quantity and,
+// for computed pricing, its price. Simple products are left unchanged.
+func resolveBundle(db *gorm.DB, product *Product, depth int) error {
+	return resolveBundles(db, []*Product{product}, depth)
+}
+
+// resolveBundles resolves every bundle in products like resolveBundle, loading the components
+// of all bundles on one level of nesting in a single query.
+func resolveBundles(db *gorm.DB, products []*Product, depth int) error {
+	bundles := make(map[uint][]*Product)
+	ids := make([]uint, 0, len(products))
+	for _, product := range products {
+		if product.Type != ProductTypeBundle {
+			continue
+		}
+		if depth > MaxBundleDepth {
+			return fmt.Errorf("%w: bundle %d is nested too deeply", ErrInvalidBundle, product.ID)
+		}
+		if _, ok := bundles[product.ID]; !ok {
+			ids = append(ids, product.ID)
+		}
+		bundles[product.ID] = append(bundles[product.ID], product)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var components []BundleComponent
+	err := db.Where("bundle_id IN ?", ids).Order("id ASC").Preload("Component").Find(&components).Error
+	if err != nil {
+		return err
+	}
+
+	nested := make([]*Product, 0, len(components))
+	for i := range components {
+		if components[i].Component != nil {
+			nested = append(nested, components[i].Component)
+		}
+	}
+	if err := resolveBundles(db, nested, depth+1); err != nil {
+		return err
+	}
+
+	byBundle := make(map[uint][]BundleComponent, len(ids))
+	for _, component := range components {
+		byBundle[component.BundleID] = append(byBundle[component.BundleID], component)
+	}
+	for _, id := range ids {
+		quantity, price := BundleAvailability(byBundle[id])
+		for _, product := range bundles[id] {
+			product.Components = byBundle[id]
+			product.Quantity = quantity
+			if product.Pricing == PricingComputed {
+				product.Price = price
+			}
+		}
+	}
+	return nil
+}
+
+// productPointers returns pointers to the elements of products, for resolveBundles.
+func productPointers(products []Product) []*Product {
+	pointers := make([]*Product, len(products))
+	for i := range products {
+		pointers[i] = &products[i]
+	}
+	return pointers
+}
+
+// checkNotBundleComponent returns ErrProductInBundle if the product is a component of a live
+// bundle, or of any bundle at all when includeDeleted is set.
+func checkNotBundleComponent(tx *gorm.DB, id int, includeDeleted bool) error {
+	query := tx.Model(&BundleComponent{}).Where("component_id = ?", id)
+	if !includeDeleted {
+		query = query.Where("bundle_id IN (?)", tx.Model(&Product{}).Select("id"))
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %d", ErrProductInBundle, id)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestProductBundles(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	// products 1-3: 1 at 99.99 x1, 2 at 9.99 x10, 3 at 19.99 x100
+	for _, product := range getSampleProductRequests() {
+		e.POST("/api/v1/products").WithJSON(product).
+			Expect().
+			Status(http.StatusCreated)
+	}
+
+	computed := ProductCreateRequest{
+		Name:       "starter kit",
+		SKU:        "kit-1",
+		Type:       ProductTypeBundle,
+		Pricing:    PricingComputed,
+		Components: []BundleComponentRequest{{ComponentID: 2, Quantity: 2}, {ComponentID: 3, Quantity: 5}},
+	}
+	kit := e.POST("/api/v1/products").WithJSON(computed).
+		Expect().
+		Status(http.StatusCreated).
+		JSON().Object()
+	kit.Value("type").String().IsEqual(ProductTypeBundle)
+	kit.Value("price").Number().IsEqual(119.93)
+	kit.Value("quantity").Number().IsEqual(5)
+	kit.Value("components").Array().Length().IsEqual(2)
+	kitID := int(kit.Value("id").Number().Raw())
+
+	testCases := []struct {
+		name           string
+		product        ProductCreateRequest
+		expectedStatus int
+	}{
+		{
+			name:           "Fixed price bundle",
+			product:        ProductCreateRequest{Name: "deluxe kit", SKU: "kit-2", Price: 100, Type: ProductTypeBundle, Components: []BundleComponentRequest{{ComponentID: 1, Quantity: 1}, {ComponentID: uint(kitID), Quantity: 1}}},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Bundle without components",
+			product:        ProductCreateRequest{Name: "empty kit", SKU: "kit-3", Price: 10, Type: ProductTypeBundle},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Fixed price bundle without price",
+			product:        ProductCreateRequest{Name: "free kit", SKU: "kit-4", Type: ProductTypeBundle, Components: []BundleComponentRequest{{ComponentID: 1, Quantity: 1}}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Missing component",
+			product:        ProductCreateRequest{Name: "ghost kit", SKU: "kit-5", Price: 10, Type: ProductTypeBundle, Components: []BundleComponentRequest{{ComponentID: 1000000, Quantity: 1}}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Duplicate component",
+			product:        ProductCreateRequest{Name: "double kit", SKU: "kit-6", Price: 10, Type: ProductTypeBundle, Components: []BundleComponentRequest{{ComponentID: 1, Quantity: 1}, {ComponentID: 1, Quantity: 2}}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Zero component quantity",
+			product:        ProductCreateRequest{Name: "zero kit", SKU: "kit-7", Price: 10, Type: ProductTypeBundle, Components: []BundleComponentRequest{{ComponentID: 1, Quantity: 0}}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Components on simple product",
+			product:        ProductCreateRequest{Name: "simple", SKU: "kit-8", Price: 10, Components: []BundleComponentRequest{{ComponentID: 1, Quantity: 1}}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.POST("/api/v1/products").WithJSON(tc.product).
+				Expect().
+				Status(tc.expectedStatus)
+		})
+	}
+
+	// product 1 has only one unit, so the nested bundle can be assembled once
+	deluxe := e.GET("/api/v1/products").WithQuery("size", "20").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("products").Array().Last().Object()
+	deluxe.Value("sku").String().IsEqual("kit-2")
+	deluxe.Value("quantity").Number().IsEqual(1)
+	deluxe.Value("price").Number().IsEqual(100)
+	deluxeID := int(deluxe.Value("id").Number().Raw())
+
+	// adding the deluxe kit to the starter kit would create a cycle
+	e.PATCH("/api/v1/products/" + strconv.Itoa(kitID)).
+		WithJSON(ProductUpdateRequest{Components: []BundleComponentRequest{{ComponentID: uint(deluxeID), Quantity: 1}}}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	// component stock and price changes flow through to the bundle
+	e.PATCH("/api/v1/products/2").WithJSON(ProductUpdateRequest{Quantity: intPtr(4), Price: floatPtr(10)}).
+		Expect().
+		Status(http.StatusOK)
+	kit = e.GET("/api/v1/products/" + strconv.Itoa(kitID)).Expect().Status(http.StatusOK).JSON().Object()
+	kit.Value("quantity").Number().IsEqual(2)
+	kit.Value("price").Number().IsEqual(119.95)
+
+	// the stored price that filters read is refreshed too
+	e.GET("/api/v1/products").WithQuery("filter", "price gt 119.94").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("products").Array().Length().IsEqual(1)
+
+	// components of live bundles can't be deleted
+	e.DELETE("/api/v1/products/2").Expect().Status(http.StatusConflict)
+	e.DELETE("/api/v1/products/" + strconv.Itoa(deluxeID)).Expect().Status(http.StatusNoContent)
+	e.DELETE("/api/v1/products/" + strconv.Itoa(kitID)).Expect().Status(http.StatusNoContent)
+	e.DELETE("/api/v1/products/2").Expect().Status(http.StatusNoContent)
+
+	// deleted products can't be used as components
+	e.POST("/api/v1/products").WithJSON(ProductCreateRequest{
+		Name: "late kit", SKU: "kit-9", Price: 10, Type: ProductTypeBundle,
+		Components: []BundleComponentRequest{{ComponentID: 2, Quantity: 1}},
+	}).Expect().Status(http.StatusBadRequest)
+}
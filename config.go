@@ -0,0 +1,553 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the service settings. It is built from DefaultConfig, then the optional config
+// file, then environment variables, each overriding the previous.
+type Config struct {
+	Server     ServerConfig     `yaml:"server" toml:"server"`
+	Log        LogConfig        `yaml:"log" toml:"log"`
+	Database   DatabaseConfig   `yaml:"database" toml:"database"`
+	Media      MediaConfig      `yaml:"media" toml:"media"`
+	Pagination PaginationConfig `yaml:"pagination" toml:"pagination"`
+	Tracing    TracingConfig    `yaml:"tracing" toml:"tracing"`
+	Auth       AuthConfig       `yaml:"auth" toml:"auth"`
+}
+
+type ServerConfig struct {
+	Addr string `yaml:"addr" toml:"addr"`
+
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// ShutdownDelay keeps serving, with readiness failing, for this long after a shutdown
+	// signal so load balancers stop routing here first. ShutdownTimeout then bounds how long
+	// in-flight requests may run.
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// RequestTimeout is the deadline given to each API request's context; zero disables it.
+	// RouteTimeouts overrides it per route, keyed by method and path template such as
+	// "POST /api/v1/products/{id}/media".
+	RequestTimeout time.Duration            `yaml:"request_timeout" toml:"request_timeout"`
+	RouteTimeouts  map[string]time.Duration `yaml:"route_timeouts" toml:"route_timeouts"`
+	// MaxBodyBytes caps JSON request bodies. Media uploads have a limit of their own.
+	MaxBodyBytes int `yaml:"max_body_bytes" toml:"max_body_bytes"`
+}
+
+type LogConfig struct {
+	// Level is one of debug, info, warn or error.
+	Level string `yaml:"level" toml:"level"`
+	// Format is console for human-readable output or json.
+	Format string `yaml:"format" toml:"format"`
+}
+
+// DatabaseConfig locates the Postgres database. DSN, when set, is used as is and the
+// individual connection fields are ignored.
+type DatabaseConfig struct {
+	DSN      string `yaml:"dsn" toml:"dsn"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"ssl_mode" toml:"ssl_mode"`
+
+	ConnectTimeout time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	// PingTimeout bounds the database check made by the readiness probe.
+	PingTimeout     time.Duration `yaml:"ping_timeout" toml:"ping_timeout"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+}
+
+type MediaConfig struct {
+	Dir string `yaml:"dir" toml:"dir"`
+	// MaxWidth, MaxHeight and MaxPixels limit the dimensions of uploaded images. They are
+	// checked against the image header before decoding, which allocates memory for every pixel.
+	MaxWidth  int `yaml:"max_width" toml:"max_width"`
+	MaxHeight int `yaml:"max_height" toml:"max_height"`
+	MaxPixels int `yaml:"max_pixels" toml:"max_pixels"`
+}
+
+type PaginationConfig struct {
+	// DefaultSize is the page size used when a request doesn't give one; MaxSize is the
+	// largest size a request may ask for.
+	DefaultSize int `yaml:"default_size" toml:"default_size"`
+	MaxSize     int `yaml:"max_size" toml:"max_size"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout (pretty-printed spans, for local testing) or otlp.
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// Endpoint is the OTLP/HTTP collector URL; the scheme selects plain HTTP or TLS.
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+type AuthConfig struct {
+	// Enabled requires an API key or token on every /api/v1 request. Turning it off leaves the
+	// API open to anyone who can reach it, so only do so behind another gateway.
+	Enabled bool      `yaml:"enabled" toml:"enabled"`
+	JWT     JWTConfig `yaml:"jwt" toml:"jwt"`
+	// Policy grants each role the permissions it lists, which are the API key scopes. Roles set
+	// in a config file are added to the defaults, replacing a default role of the same name.
+	Policy map[string][]string `yaml:"policy" toml:"policy"`
+}
+
+// JWTConfig sets how bearer tokens from the SSO are validated. Tokens are accepted once a
+// JWKS file or URL is set.
+type JWTConfig struct {
+	JWKSFile string `yaml:"jwks_file" toml:"jwks_file"`
+	JWKSURL  string `yaml:"jwks_url" toml:"jwks_url"`
+	// JWKSRefresh is how long keys fetched from JWKSURL are used before fetching them again.
+	// A token signed with an unknown key triggers an earlier fetch.
+	JWKSRefresh time.Duration `yaml:"jwks_refresh" toml:"jwks_refresh"`
+	Issuer      string        `yaml:"issuer" toml:"issuer"`
+	Audience    string        `yaml:"audience" toml:"audience"`
+	// Leeway allows for clock skew when checking expiry and not-before times.
+	Leeway time.Duration `yaml:"leeway" toml:"leeway"`
+	// RolesClaim is the claim holding the caller's roles, as a list or a space-separated
+	// string. Dots reach into nested objects, e.g. realm_access.roles.
+	RolesClaim string `yaml:"roles_claim" toml:"roles_claim"`
+	// RoleMapping renames claim values to policy roles, e.g. an SSO group to "editor". When
+	// set, values it doesn't list are ignored; when empty, values are used as roles as is.
+	RoleMapping map[string]string `yaml:"role_mapping" toml:"role_mapping"`
+}
+
+// Enabled reports whether bearer tokens are accepted.
+func (c JWTConfig) Enabled() bool {
+	return c.JWKSFile != "" || c.JWKSURL != ""
+}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+func DefaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   10 * time.Second,
+			RequestTimeout:    10 * time.Second,
+			MaxBodyBytes:      1 << 20,
+		},
+		Log: LogConfig{Level: "info", Format: "console"},
+		Database: DatabaseConfig{
+			Port:            5432,
+			SSLMode:         "disable",
+			ConnectTimeout:  5 * time.Second,
+			PingTimeout:     2 * time.Second,
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Media:      MediaConfig{Dir: "media", MaxWidth: 8192, MaxHeight: 8192, MaxPixels: 40_000_000},
+		Pagination: PaginationConfig{DefaultSize: DefaultPageSize, MaxSize: 100},
+		Tracing: TracingConfig{
+			Exporter:    TracingExporterNone,
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "simpler-test",
+			SampleRatio: 1,
+		},
+		Auth: AuthConfig{
+			Enabled: true,
+			JWT: JWTConfig{
+				JWKSRefresh: 15 * time.Minute,
+				Leeway:      30 * time.Second,
+				RolesClaim:  "roles",
+			},
+			Policy: map[string][]string{
+				"viewer": {ScopeProductsRead},
+				"editor": {ScopeProductsWrite},
+				"admin":  {ScopeProductsAdmin},
+			},
+		},
+	}
+}
+
+// ConfigError lists every problem found while loading the configuration, so they can all be
+// fixed at once.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func (e *ConfigError) add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// LoadConfig builds the configuration from the defaults, the YAML or TOML file at path (skipped
+// when path is empty) and the environment, then validates it.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	problems := &ConfigError{}
+	cfg.applyEnv(problems)
+	cfg.validate(problems)
+	if len(problems.Problems) > 0 {
+		return cfg, problems
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("failed to parse config file %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q, expected .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	return nil
+}
+
+// applyEnv overrides the settings with the environment variables that are set. Values that
+// can't be parsed are reported and leave the setting unchanged.
+func (c *Config) applyEnv(problems *ConfigError) {
+	envString("LISTEN_ADDR", &c.Server.Addr)
+	envDuration("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout, problems)
+	envDuration("SERVER_READ_HEADER_TIMEOUT", &c.Server.ReadHeaderTimeout, problems)
+	envDuration("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout, problems)
+	envDuration("SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout, problems)
+	envDuration("SHUTDOWN_DELAY", &c.Server.ShutdownDelay, problems)
+	envDuration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout, problems)
+	envDuration("REQUEST_TIMEOUT", &c.Server.RequestTimeout, problems)
+	envInt("MAX_BODY_BYTES", &c.Server.MaxBodyBytes, problems)
+	envString("LOG_LEVEL", &c.Log.Level)
+	envString("LOG_FORMAT", &c.Log.Format)
+
+	envString("DATABASE_URL", &c.Database.DSN)
+	envString("DB_HOST", &c.Database.Host)
+	envInt("DB_PORT", &c.Database.Port, problems)
+	envString("DB_USER", &c.Database.User)
+	envString("DB_PASSWORD", &c.Database.Password)
+	envString("DB_NAME", &c.Database.Name)
+	envString("DB_SSLMODE", &c.Database.SSLMode)
+	envDuration("DB_CONNECT_TIMEOUT", &c.Database.ConnectTimeout, problems)
+	envDuration("DB_PING_TIMEOUT", &c.Database.PingTimeout, problems)
+	envInt("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns, problems)
+	envInt("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns, problems)
+	envDuration("DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime, problems)
+	envDuration("DB_CONN_MAX_IDLE_TIME", &c.Database.ConnMaxIdleTime, problems)
+
+	envString("MEDIA_DIR", &c.Media.Dir)
+	envInt("MEDIA_MAX_WIDTH", &c.Media.MaxWidth, problems)
+	envInt("MEDIA_MAX_HEIGHT", &c.Media.MaxHeight, problems)
+	envInt("MEDIA_MAX_PIXELS", &c.Media.MaxPixels, problems)
+
+	envInt("PAGE_SIZE_DEFAULT", &c.Pagination.DefaultSize, problems)
+	envInt("PAGE_SIZE_MAX", &c.Pagination.MaxSize, problems)
+
+	envString("TRACING_EXPORTER", &c.Tracing.Exporter)
+	envString("TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	envString("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+	envFloat("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio, problems)
+	envBool("AUTH_ENABLED", &c.Auth.Enabled, problems)
+	envString("AUTH_JWKS_FILE", &c.Auth.JWT.JWKSFile)
+	envString("AUTH_JWKS_URL", &c.Auth.JWT.JWKSURL)
+	envString("AUTH_JWT_ISSUER", &c.Auth.JWT.Issuer)
+	envString("AUTH_JWT_AUDIENCE", &c.Auth.JWT.Audience)
+	envString("AUTH_JWT_ROLES_CLAIM", &c.Auth.JWT.RolesClaim)
+}
+
+func envString(name string, target *string) {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		*target = value
+	}
+}
+
+func envInt(name string, target *int, problems *ConfigError) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		problems.add("%s: %q is not an integer", name, value)
+		return
+	}
+	*target = parsed
+}
+
+func envFloat(name string, target *float64, problems *ConfigError) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		problems.add("%s: %q is not a number", name, value)
+		return
+	}
+	*target = parsed
+}
+
+func envBool(name string, target *bool, problems *ConfigError) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		problems.add("%s: %q is not true or false", name, value)
+		return
+	}
+	*target = parsed
+}
+
+func envDuration(name string, target *time.Duration, problems *ConfigError) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		problems.add("%s: %q is not a duration such as 30s or 5m", name, value)
+		return
+	}
+	*target = parsed
+}
+
+func (c *Config) validate(problems *ConfigError) {
+	if c.Server.Addr == "" {
+		problems.add("server.addr is required")
+	}
+	if c.Server.ReadTimeout < 0 || c.Server.ReadHeaderTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 || c.Server.ShutdownDelay < 0 || c.Server.RequestTimeout < 0 {
+		problems.add("server timeouts must not be negative")
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems.add("server.shutdown_timeout must be positive")
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		problems.add("server.max_body_bytes must be positive")
+	}
+	for _, route := range sortedKeys(c.Server.RouteTimeouts) {
+		if !validRouteKey(route) {
+			problems.add("server.route_timeouts key %q must be a method and path such as \"GET /api/v1/products\"", route)
+		}
+		if c.Server.RouteTimeouts[route] <= 0 {
+			problems.add("server.route_timeouts %q must be positive", route)
+		}
+	}
+
+	if _, err := zapcore.ParseLevel(c.Log.Level); err != nil {
+		problems.add("log.level %q must be one of debug, info, warn, error", c.Log.Level)
+	}
+	if c.Log.Format != "console" && c.Log.Format != "json" {
+		problems.add("log.format %q must be console or json", c.Log.Format)
+	}
+
+	db := c.Database
+	if db.DSN == "" {
+		if db.Host == "" {
+			problems.add("database.host (DB_HOST) is required unless database.dsn (DATABASE_URL) is set")
+		}
+		if db.User == "" {
+			problems.add("database.user (DB_USER) is required unless database.dsn (DATABASE_URL) is set")
+		}
+		if db.Name == "" {
+			problems.add("database.name (DB_NAME) is required unless database.dsn (DATABASE_URL) is set")
+		}
+		if db.Port < 1 || db.Port > 65535 {
+			problems.add("database.port %d must be between 1 and 65535", db.Port)
+		}
+		if !containsString(sslModes, db.SSLMode) {
+			problems.add("database.ssl_mode %q must be one of %s", db.SSLMode, strings.Join(sslModes, ", "))
+		}
+		if db.ConnectTimeout < 0 {
+			problems.add("database.connect_timeout must not be negative")
+		}
+	}
+	if db.PingTimeout <= 0 {
+		problems.add("database.ping_timeout must be positive")
+	}
+	if db.MaxOpenConns < 0 {
+		problems.add("database.max_open_conns must not be negative")
+	}
+	if db.MaxIdleConns < 0 {
+		problems.add("database.max_idle_conns must not be negative")
+	}
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		problems.add("database.max_idle_conns %d must not exceed database.max_open_conns %d", db.MaxIdleConns, db.MaxOpenConns)
+	}
+	if db.ConnMaxLifetime < 0 {
+		problems.add("database.conn_max_lifetime must not be negative")
+	}
+	if db.ConnMaxIdleTime < 0 {
+		problems.add("database.conn_max_idle_time must not be negative")
+	}
+
+	if c.Media.Dir == "" {
+		problems.add("media.dir is required")
+	}
+	if c.Media.MaxWidth < 1 || c.Media.MaxHeight < 1 || c.Media.MaxPixels < 1 {
+		problems.add("media.max_width, media.max_height and media.max_pixels must be at least 1")
+	}
+
+	if c.Pagination.DefaultSize < 1 {
+		problems.add("pagination.default_size must be at least 1")
+	}
+	if c.Pagination.MaxSize < 1 {
+		problems.add("pagination.max_size must be at least 1")
+	}
+	if c.Pagination.DefaultSize > c.Pagination.MaxSize {
+		problems.add("pagination.default_size %d must not exceed pagination.max_size %d", c.Pagination.DefaultSize, c.Pagination.MaxSize)
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems.add("tracing.endpoint %q must be an http or https URL", c.Tracing.Endpoint)
+		}
+	default:
+		problems.add("tracing.exporter %q must be one of none, stdout, otlp", c.Tracing.Exporter)
+	}
+	if c.Tracing.ServiceName == "" {
+		problems.add("tracing.service_name is required")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems.add("tracing.sample_ratio %v must be between 0 and 1", c.Tracing.SampleRatio)
+	}
+
+	jwt := c.Auth.JWT
+	if jwt.Enabled() {
+		if jwt.JWKSFile != "" && jwt.JWKSURL != "" {
+			problems.add("auth.jwt.jwks_file (AUTH_JWKS_FILE) and auth.jwt.jwks_url (AUTH_JWKS_URL) are mutually exclusive")
+		}
+		if jwt.JWKSURL != "" {
+			if u, err := url.Parse(jwt.JWKSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				problems.add("auth.jwt.jwks_url %q must be an http or https URL", jwt.JWKSURL)
+			}
+		}
+		if jwt.Issuer == "" {
+			problems.add("auth.jwt.issuer (AUTH_JWT_ISSUER) is required when tokens are accepted")
+		}
+		if jwt.Audience == "" {
+			problems.add("auth.jwt.audience (AUTH_JWT_AUDIENCE) is required when tokens are accepted")
+		}
+		if jwt.RolesClaim == "" {
+			problems.add("auth.jwt.roles_claim is required when tokens are accepted")
+		}
+		if jwt.JWKSRefresh <= 0 || jwt.Leeway < 0 {
+			problems.add("auth.jwt.jwks_refresh must be positive and auth.jwt.leeway not negative")
+		}
+	}
+
+	for _, role := range sortedKeys(c.Auth.Policy) {
+		for _, permission := range c.Auth.Policy[role] {
+			if _, ok := impliedScopes[permission]; !ok {
+				problems.add("auth.policy %q grants unknown permission %q", role, permission)
+			}
+		}
+	}
+	for _, value := range sortedKeys(jwt.RoleMapping) {
+		if _, ok := c.Auth.Policy[jwt.RoleMapping[value]]; !ok {
+			problems.add("auth.jwt.role_mapping %q maps to %q, which auth.policy doesn't define", value, jwt.RoleMapping[value])
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in order, so problems are reported in a stable order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ConnectionString returns DSN when set, or a key/value connection string built from the
+// individual fields.
+func (c DatabaseConfig) ConnectionString() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+
+	parts := []string{
+		"host=" + dsnValue(c.Host),
+		"port=" + strconv.Itoa(c.Port),
+		"user=" + dsnValue(c.User),
+		"password=" + dsnValue(c.Password),
+		"dbname=" + dsnValue(c.Name),
+		"sslmode=" + dsnValue(c.SSLMode),
+	}
+	if c.ConnectTimeout > 0 {
+		// The timeout is given in whole seconds, and 0 would mean waiting forever, so round up.
+		seconds := (c.ConnectTimeout + time.Second - 1) / time.Second
+		parts = append(parts, "connect_timeout="+strconv.FormatInt(int64(seconds), 10))
+	}
+	return strings.Join(parts, " ")
+}
+
+// dsnValue quotes a key/value connection string value, escaping quotes and backslashes.
+func dsnValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// NewLogger builds a logger writing at the configured level, as human-readable console output
+// or as JSON.
+func NewLogger(cfg LogConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	zapConfig := zap.NewDevelopmentConfig()
+	if cfg.Format == "json" {
+		zapConfig = zap.NewProductionConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	return zapConfig.Build()
+}
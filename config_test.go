@@ -0,0 +1,268 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var configEnvVars = []string{
+	"LISTEN_ADDR", "SERVER_READ_TIMEOUT", "SERVER_READ_HEADER_TIMEOUT", "SERVER_WRITE_TIMEOUT",
+	"SERVER_IDLE_TIMEOUT", "SHUTDOWN_DELAY", "SHUTDOWN_TIMEOUT", "REQUEST_TIMEOUT", "MAX_BODY_BYTES", "LOG_LEVEL", "LOG_FORMAT", "DATABASE_URL", "DB_HOST",
+	"DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE", "DB_CONNECT_TIMEOUT", "DB_PING_TIMEOUT", "DB_MAX_OPEN_CONNS",
+	"DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME", "DB_CONN_MAX_IDLE_TIME", "MEDIA_DIR", "MEDIA_MAX_WIDTH",
+	"MEDIA_MAX_HEIGHT", "MEDIA_MAX_PIXELS",
+	"PAGE_SIZE_DEFAULT", "PAGE_SIZE_MAX", "TRACING_EXPORTER", "TRACING_ENDPOINT", "TRACING_SERVICE_NAME",
+	"TRACING_SAMPLE_RATIO", "AUTH_ENABLED", "AUTH_JWKS_FILE", "AUTH_JWKS_URL", "AUTH_JWT_ISSUER", "AUTH_JWT_AUDIENCE",
+	"AUTH_JWT_ROLES_CLAIM",
+}
+
+// clearConfigEnv blanks the config variables for the test; empty variables are ignored.
+func clearConfigEnv(t *testing.T) {
+	for _, name := range configEnvVars {
+		t.Setenv(name, "")
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigSources(t *testing.T) {
+	yamlFile := `
+server:
+  addr: ":9090"
+  route_timeouts:
+    POST /api/v1/products/{id}/media: 30s
+log:
+  level: debug
+database:
+  host: filehost
+  user: fileuser
+  name: catalog
+  max_open_conns: 10
+  conn_max_lifetime: 1h
+pagination:
+  max_size: 50
+auth:
+  enabled: false
+  jwt:
+    jwks_url: https://sso.example.com/jwks.json
+    issuer: https://sso.example.com
+    audience: simpler-test
+    roles_claim: realm_access.roles
+    role_mapping:
+      catalog-team: editor
+  policy:
+    auditor: [products:read]
+`
+	tomlFile := `
+[server]
+addr = ":9090"
+
+[server.route_timeouts]
+"POST /api/v1/products/{id}/media" = "30s"
+
+[log]
+level = "debug"
+
+[database]
+host = "filehost"
+user = "fileuser"
+name = "catalog"
+max_open_conns = 10
+conn_max_lifetime = "1h"
+
+[pagination]
+max_size = 50
+
+[auth]
+enabled = false
+
+[auth.jwt]
+jwks_url = "https://sso.example.com/jwks.json"
+issuer = "https://sso.example.com"
+audience = "simpler-test"
+roles_claim = "realm_access.roles"
+
+[auth.jwt.role_mapping]
+catalog-team = "editor"
+
+[auth.policy]
+auditor = ["products:read"]
+`
+	for _, tt := range []struct{ name, content string }{{"config.yaml", yamlFile}, {"config.toml", tomlFile}} {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			t.Setenv("DB_HOST", "envhost")
+			t.Setenv("DB_MAX_IDLE_CONNS", "2")
+
+			cfg, err := LoadConfig(writeConfigFile(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Server.Addr != ":9090" || cfg.Log.Level != "debug" || cfg.Log.Format != "console" {
+				t.Errorf("server/log incorrect. got %+v %+v", cfg.Server, cfg.Log)
+			}
+			if cfg.Server.RequestTimeout != 10*time.Second || cfg.Server.RouteTimeouts["POST /api/v1/products/{id}/media"] != 30*time.Second {
+				t.Errorf("request timeouts incorrect. got %v %v", cfg.Server.RequestTimeout, cfg.Server.RouteTimeouts)
+			}
+			if cfg.Database.Host != "envhost" {
+				t.Errorf("environment should override the file. got host %q", cfg.Database.Host)
+			}
+			if cfg.Database.User != "fileuser" || cfg.Database.Port != 5432 || cfg.Database.MaxOpenConns != 10 || cfg.Database.MaxIdleConns != 2 {
+				t.Errorf("database incorrect. got %+v", cfg.Database)
+			}
+			if cfg.Database.ConnMaxLifetime != time.Hour {
+				t.Errorf("conn_max_lifetime incorrect. got %v", cfg.Database.ConnMaxLifetime)
+			}
+			if cfg.Pagination.DefaultSize != DefaultPageSize || cfg.Pagination.MaxSize != 50 {
+				t.Errorf("pagination incorrect. got %+v", cfg.Pagination)
+			}
+			if cfg.Auth.Enabled {
+				t.Errorf("auth should be disabled by the file")
+			}
+			if !cfg.Auth.JWT.Enabled() || cfg.Auth.JWT.RolesClaim != "realm_access.roles" || cfg.Auth.JWT.RoleMapping["catalog-team"] != "editor" || cfg.Auth.JWT.Leeway != 30*time.Second {
+				t.Errorf("jwt incorrect. got %+v", cfg.Auth.JWT)
+			}
+			if len(cfg.Auth.Policy["auditor"]) != 1 || len(cfg.Auth.Policy["editor"]) != 1 {
+				t.Errorf("file policy should be added to the defaults. got %v", cfg.Auth.Policy)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	var tests = []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"unknown yaml key", "config.yaml", "server:\n  port: 8080\n"},
+		{"unknown toml key", "config.toml", "[server]\nport = 8080\n"},
+		{"malformed yaml", "config.yml", "server: [\n"},
+		{"bad route timeout key", "config.yaml", "database:\n  dsn: postgres://u@db/n\nserver:\n  route_timeouts:\n    /api/v1/products: 5s\n"},
+		{"non-positive route timeout", "config.yaml", "database:\n  dsn: postgres://u@db/n\nserver:\n  route_timeouts:\n    GET /api/v1/products: 0s\n"},
+		{"unknown policy permission", "config.yaml", "database:\n  dsn: postgres://u@db/n\nauth:\n  policy:\n    viewer: [products:everything]\n"},
+		{"role mapping to an unknown role", "config.yaml", "database:\n  dsn: postgres://u@db/n\nauth:\n  jwt:\n    role_mapping:\n      staff: owner\n"},
+		{"unsupported extension", "config.json", "{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			if _, err := LoadConfig(writeConfigFile(t, tt.file, tt.content)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	var tests = []struct {
+		name     string
+		env      map[string]string
+		problems []string
+	}{
+		{"valid parts", map[string]string{"DB_HOST": "db", "DB_USER": "u", "DB_NAME": "n"}, nil},
+		{"valid dsn", map[string]string{"DATABASE_URL": "postgres://u@db/n"}, nil},
+		{"missing database", nil, []string{"database.host", "database.user", "database.name"}},
+		{"invalid values", map[string]string{
+			"DATABASE_URL": "postgres://u@db/n", "LOG_LEVEL": "loud", "LOG_FORMAT": "xml",
+			"DB_MAX_OPEN_CONNS": "2", "DB_MAX_IDLE_CONNS": "3", "PAGE_SIZE_DEFAULT": "200", "MEDIA_MAX_PIXELS": "0",
+		}, []string{"log.level", "log.format", "database.max_idle_conns", "media.max_width", "pagination.default_size"}},
+		{"unparseable values", map[string]string{
+			"DB_HOST": "db", "DB_USER": "u", "DB_NAME": "n", "DB_PORT": "abc", "DB_CONNECT_TIMEOUT": "5",
+			"AUTH_ENABLED": "maybe",
+		}, []string{"DB_PORT", "DB_CONNECT_TIMEOUT", "AUTH_ENABLED"}},
+		{"bad server timeouts", map[string]string{
+			"DATABASE_URL": "postgres://u@db/n", "SERVER_WRITE_TIMEOUT": "-1s", "SHUTDOWN_TIMEOUT": "0s", "REQUEST_TIMEOUT": "-5s",
+			"MAX_BODY_BYTES": "0",
+		}, []string{"server timeouts", "server.shutdown_timeout", "server.max_body_bytes"}},
+		{"bad tracing", map[string]string{
+			"DATABASE_URL": "postgres://u@db/n", "TRACING_EXPORTER": "otlp", "TRACING_ENDPOINT": "localhost:4318",
+			"TRACING_SAMPLE_RATIO": "1.5",
+		}, []string{"tracing.endpoint", "tracing.sample_ratio"}},
+		{"unknown tracing exporter", map[string]string{
+			"DATABASE_URL": "postgres://u@db/n", "TRACING_EXPORTER": "jaeger",
+		}, []string{"tracing.exporter"}},
+		{"valid jwt", map[string]string{
+			"DATABASE_URL": "postgres://u@db/n", "AUTH_JWKS_URL": "https://sso.example.com/jwks.json",
+			"AUTH_JWT_ISSUER": "https://sso.example.com", "AUTH_JWT_AUDIENCE": "simpler-test",
+		}, nil},
+		{"incomplete jwt", map[string]string{
+			"DATABASE_URL": "postgres://u@db/n", "AUTH_JWKS_FILE": "jwks.json", "AUTH_JWKS_URL": "ftp://sso.example.com/jwks.json",
+		}, []string{"auth.jwt.jwks_file", "auth.jwt.jwks_url", "auth.jwt.issuer", "auth.jwt.audience"}},
+		{"bad ssl mode and port", map[string]string{
+			"DB_HOST": "db", "DB_USER": "u", "DB_NAME": "n", "DB_PORT": "70000", "DB_SSLMODE": "sometimes",
+		}, []string{"database.port", "database.ssl_mode"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := LoadConfig("")
+			if tt.problems == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			configErr, ok := err.(*ConfigError)
+			if !ok {
+				t.Fatalf("expected a ConfigError. got %v", err)
+			}
+			if len(configErr.Problems) != len(tt.problems) {
+				t.Fatalf("problem count incorrect. got %v, want problems for %v", configErr.Problems, tt.problems)
+			}
+			for i, prefix := range tt.problems {
+				if !strings.HasPrefix(configErr.Problems[i], prefix) {
+					t.Errorf("problem %d incorrect. got %q, want it to start with %q", i, configErr.Problems[i], prefix)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectionString(t *testing.T) {
+	cfg := DefaultConfig().Database
+	cfg.Host = "db"
+	cfg.User = "catalog"
+	cfg.Password = `it's a secret`
+	cfg.Name = "products"
+
+	want := `host='db' port=5432 user='catalog' password='it\'s a secret' dbname='products' sslmode='disable' connect_timeout=5`
+	if got := cfg.ConnectionString(); got != want {
+		t.Errorf("connection string incorrect. got %s, want %s", got, want)
+	}
+
+	// connect_timeout is in whole seconds and rounded up, so short timeouts don't become 0
+	for timeout, want := range map[time.Duration]string{
+		500 * time.Millisecond:  "connect_timeout=1",
+		1500 * time.Millisecond: "connect_timeout=2",
+		2 * time.Second:         "connect_timeout=2",
+	} {
+		cfg.ConnectTimeout = timeout
+		if got := cfg.ConnectionString(); !strings.HasSuffix(got, want) {
+			t.Errorf("connection string for %s timeout incorrect. got %s, want %s", timeout, got, want)
+		}
+	}
+
+	cfg.DSN = "postgres://catalog@db/products"
+	if got := cfg.ConnectionString(); got != cfg.DSN {
+		t.Errorf("DSN should be used as is. got %s", got)
+	}
+}
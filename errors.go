@@ -3,7 +3,34 @@ package main
 import "errors"
 
 var (
-	ErrNotFound     = errors.New("product not found")
-	ErrDuplicateSKU = errors.New("product with this SKU already exists")
-	ErrOutOfRange   = errors.New("page number out of range")
+	ErrNotFound         = errors.New("product not found")
+	ErrDuplicateSKU     = errors.New("product with this SKU already exists")
+	ErrDuplicateBarcode = errors.New("product with this barcode already exists")
+	ErrInvalidBarcode   = errors.New("invalid GTIN barcode")
+	ErrOutOfRange       = errors.New("page number out of range")
+	ErrInvalidPatch     = errors.New("invalid patch")
+	ErrPatchTestFailed  = errors.New("patch test operation failed")
+
+	ErrSupplierNotFound        = errors.New("supplier not found")
+	ErrProductSupplierNotFound = errors.New("supplier does not supply this product")
+
+	ErrInvalidBundle   = errors.New("invalid bundle")
+	ErrProductInBundle = errors.New("product is a component of a bundle")
+
+	ErrMediaNotFound           = errors.New("media not found")
+	ErrBlobNotFound            = errors.New("blob not found")
+	ErrUnsupportedMediaType    = errors.New("unsupported media type")
+	ErrMediaTooLarge           = errors.New("media file is too large")
+	ErrInvalidImage            = errors.New("image could not be decoded")
+	ErrMediaPositionOutOfRange = errors.New("media position out of range")
+
+	ErrAttributeDefinitionNotFound = errors.New("attribute definition not found")
+	ErrDuplicateAttribute          = errors.New("attribute is already defined for this category")
+
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrAPIKeyRevoked  = errors.New("API key has been revoked")
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrJWKSUnavailable = errors.New("token signing keys are unavailable")
 )
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestExpandRelations(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	for _, req := range getSampleProductRequests() {
+		e.POST("/api/v1/products").WithJSON(req).Expect().Status(http.StatusCreated)
+	}
+	e.POST("/api/v1/products/1/media").
+		WithMultipart().
+		WithFileBytes("file", "first.png", samplePNG(t, 10, 10)).
+		Expect().
+		Status(http.StatusCreated)
+	e.POST("/api/v1/suppliers").WithJSON(SupplierCreateRequest{Name: "Acme"}).Expect().Status(http.StatusCreated)
+	e.PUT("/api/v1/products/1/suppliers/1").
+		WithJSON(ProductSupplierRequest{SupplierSKU: "ACME-1", CostPrice: 60}).
+		Expect().
+		Status(http.StatusCreated)
+
+	// relations are only embedded when expanded
+	e.GET("/api/v1/products/1").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().NotContainsKey("media").NotContainsKey("suppliers")
+
+	product := e.GET("/api/v1/products/1").WithQuery("expand", "media,suppliers.supplier").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	product.Value("media").Array().Length().IsEqual(1)
+	suppliers := product.Value("suppliers").Array()
+	suppliers.Length().IsEqual(1)
+	suppliers.Value(0).Object().Value("supplier").Object().Value("name").String().IsEqual("Acme")
+
+	// expanding suppliers alone doesn't embed the supplier records
+	e.GET("/api/v1/products/1").WithQuery("expand", "suppliers").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("suppliers").Array().Value(0).Object().NotContainsKey("supplier")
+
+	// expanded relations are kept in a sparse fieldset
+	e.GET("/api/v1/products/1").WithQuery("fields", "name").WithQuery("expand", "media").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Keys().ContainsOnly("id", "name", "media")
+
+	products := e.GET("/api/v1/products").WithQuery("expand", "media,suppliers").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("products").Array()
+	products.Length().IsEqual(3)
+	products.Value(0).Object().Value("media").Array().Length().IsEqual(1)
+	products.Value(1).Object().NotContainsKey("media")
+
+	testCases := []struct {
+		name   string
+		expand string
+	}{
+		{"Unknown relation", "warehouse"},
+		{"Too deep", "suppliers.supplier.products"},
+		{"Empty relation", "media,"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.GET("/api/v1/products/1").WithQuery("expand", tc.expand).Expect().Status(http.StatusBadRequest)
+			e.GET("/api/v1/products").WithQuery("expand", tc.expand).Expect().Status(http.StatusBadRequest)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestSparseFieldsets(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	for _, req := range getSampleProductRequests() {
+		e.POST("/api/v1/products").WithJSON(req).Expect().Status(http.StatusCreated)
+	}
+	// a bundle of two of product 2, which has 10 in stock
+	e.POST("/api/v1/products").WithJSON(ProductCreateRequest{
+		Name:       "bundle",
+		SKU:        "bundle-1",
+		Type:       ProductTypeBundle,
+		Pricing:    PricingComputed,
+		Components: []BundleComponentRequest{{ComponentID: 2, Quantity: 2}},
+	}).Expect().Status(http.StatusCreated)
+
+	product := e.GET("/api/v1/products/1").WithQuery("fields", "name,price").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	product.Keys().ContainsOnly("id", "name", "price")
+	product.Value("price").Number().IsEqual(99.99)
+
+	// derived bundle fields are still computed from the components
+	bundle := e.GET("/api/v1/products/4").WithQuery("fields", "quantity,price").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	bundle.Keys().ContainsOnly("id", "quantity", "price")
+	bundle.Value("quantity").Number().IsEqual(5)
+	bundle.Value("price").Number().IsEqual(19.98)
+
+	e.GET("/api/v1/products/sku/5678").WithQuery("fields", "sku").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Keys().ContainsOnly("id", "sku")
+
+	list := e.GET("/api/v1/products").WithQuery("fields", "name").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	list.Value("total_count").Number().IsEqual(4)
+	products := list.Value("products").Array()
+	products.Length().IsEqual(4)
+	for _, p := range products.Iter() {
+		p.Object().Keys().ContainsOnly("id", "name")
+	}
+
+	e.GET("/api/v1/products/1").WithQuery("fields", "name,secret").Expect().Status(http.StatusBadRequest)
+	e.GET("/api/v1/products").WithQuery("fields", "name,").Expect().Status(http.StatusBadRequest)
+
+	// relations are only loaded when expanded, so naming one in fields alone is rejected
+	e.GET("/api/v1/products/1").WithQuery("fields", "media").Expect().Status(http.StatusBadRequest)
+	e.GET("/api/v1/products").WithQuery("fields", "name,suppliers").Expect().Status(http.StatusBadRequest)
+	e.GET("/api/v1/products/1").WithQuery("fields", "media").WithQuery("expand", "media").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().NotContainsKey("name")
+}
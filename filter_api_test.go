@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestFilterExpressions(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	for _, req := range getSampleProductRequests() {
+		e.POST("/api/v1/products").WithJSON(req).Expect().Status(http.StatusCreated)
+	}
+
+	testCases := []struct {
+		name        string
+		filter      string
+		expectedIDs []int
+	}{
+		{"Comparison", `price gt 10`, []int{1, 3}},
+		{"Grouping", `price gt 10 and (category eq "product > subtype" or quantity lt 5)`, []int{1}},
+		{"Or", `quantity eq 1 or quantity eq 100`, []int{1, 3}},
+		{"Not", `not quantity ge 10`, []int{1}},
+		{"In", `sku in ("1234", "5678", "nope")`, []int{1, 2}},
+		{"Contains ignores case", `name contains "SECOND"`, []int{2}},
+		{"Values are parameters", `name eq "x' or 1=1 --"`, []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			products := e.GET("/api/v1/products").WithQuery("filter", tc.filter).
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().Value("products").Array()
+			products.Length().IsEqual(len(tc.expectedIDs))
+			for i, id := range tc.expectedIDs {
+				products.Value(i).Object().Value("id").Number().IsEqual(id)
+			}
+		})
+	}
+
+	// filter expressions combine with the other list filters
+	e.POST("/api/v1/products/1/discontinue").Expect().Status(http.StatusOK)
+	e.GET("/api/v1/products").WithQuery("filter", `price gt 10`).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("total_count").Number().IsEqual(1)
+
+	// an expression on status replaces the default of active products only
+	e.GET("/api/v1/products").WithQuery("filter", `status eq "discontinued"`).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("products").Array().Length().IsEqual(1)
+	e.GET("/api/v1/products").WithQuery("filter", `status eq "discontinued"`).WithQuery("status", StatusActive).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("products").Array().IsEmpty()
+
+	errorCases := []struct {
+		name            string
+		filter          string
+		expectedMessage string
+	}{
+		{"Unknown field", `secret eq "x"`, `position 1 ("secret")`},
+		{"Type mismatch", `price gt "ten"`, `position 10 ("\"ten\"")`},
+		{"Unbalanced parentheses", `(price gt 10`, "end of expression"},
+	}
+
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.GET("/api/v1/products").WithQuery("filter", tc.filter).
+				Expect().
+				Status(http.StatusBadRequest).
+				Body().Contains(tc.expectedMessage)
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter expressions select products with a small boolean grammar, e.g.
+//
+//	price gt 10 and (category eq "toys" or quantity lt 5)
+//
+// The grammar is
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field op literal | field "in" "(" literal { "," literal } ")"
+//	op         = "eq" | "ne" | "gt" | "ge" | "lt" | "le" | "contains"
+//	literal    = string | number
+//
+// Strings are double-quoted with \" and \\ escapes; timestamps are strings in RFC 3339 or
+// YYYY-MM-DD form. Keywords are case-insensitive. Fields and their types are whitelisted in
+// filterFields, and every value is passed to the database as a query parameter.
+
+const (
+	MaxFilterLength = 1000
+	MaxFilterDepth  = 16
+)
+
+type filterFieldType int
+
+const (
+	filterString filterFieldType = iota
+	filterInteger
+	filterNumber
+	filterTime
+)
+
+func (t filterFieldType) String() string {
+	switch t {
+	case filterInteger:
+		return "integer"
+	case filterNumber:
+		return "number"
+	case filterTime:
+		return "timestamp"
+	default:
+		return "string"
+	}
+}
+
+// filterFields whitelists the product columns that can be filtered on. A bundle's stored
+// quantity is always 0 because its stock is derived from its components.
+var filterFields = map[string]filterFieldType{
+	"id":          filterInteger,
+	"name":        filterString,
+	"description": filterString,
+	"sku":         filterString,
+	"barcode":     filterString,
+	"price":       filterNumber,
+	"quantity":    filterInteger,
+	"category":    filterString,
+	"status":      filterString,
+	"type":        filterString,
+	"pricing":     filterString,
+	"created_at":  filterTime,
+	"updated_at":  filterTime,
+}
+
+var filterOperators = map[string]string{
+	"eq":       "=",
+	"ne":       "<>",
+	"gt":       ">",
+	"ge":       ">=",
+	"lt":       "<",
+	"le":       "<=",
+	"contains": "ILIKE",
+	"in":       "IN",
+}
+
+// FilterError reports a problem with a filter expression and the token it was found at.
+type FilterError struct {
+	// Pos is the 1-based position of the offending token in the expression.
+	Pos     int
+	Token   string
+	Message string
+}
+
+func (e *FilterError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("invalid filter: %s at end of expression", e.Message)
+	}
+	return fmt.Sprintf("invalid filter: %s at position %d (%q)", e.Message, e.Pos, e.Token)
+}
+
+// FilterExpr is a node of a parsed filter expression.
+type FilterExpr interface {
+	// SQL returns the WHERE condition for the node and its parameters.
+	SQL() (string, []interface{})
+}
+
+// FilterLogical combines two expressions with AND or OR.
+type FilterLogical struct {
+	Op          string
+	Left, Right FilterExpr
+}
+
+func (e FilterLogical) SQL() (string, []interface{}) {
+	left, leftArgs := e.Left.SQL()
+	right, rightArgs := e.Right.SQL()
+	return "(" + left + " " + e.Op + " " + right + ")", append(leftArgs, rightArgs...)
+}
+
+// FilterNot negates an expression.
+type FilterNot struct {
+	Expr FilterExpr
+}
+
+func (e FilterNot) SQL() (string, []interface{}) {
+	sql, args := e.Expr.SQL()
+	return "NOT " + sql, args
+}
+
+// FilterComparison compares a field with one or more values of the field's type.
+type FilterComparison struct {
+	Field  string
+	Op     string
+	Values []interface{}
+}
+
+func (e FilterComparison) SQL() (string, []interface{}) {
+	switch e.Op {
+	case "in":
+		return "(" + e.Field + " IN ?)", []interface{}{e.Values}
+	case "contains":
+		return "(" + e.Field + " ILIKE ?)", []interface{}{"%" + escapeLike(e.Values[0].(string)) + "%"}
+	default:
+		return "(" + e.Field + " " + filterOperators[e.Op] + " ?)", e.Values
+	}
+}
+
+// FilterReferences reports whether the expression compares field anywhere.
+func FilterReferences(expr FilterExpr, field string) bool {
+	switch e := expr.(type) {
+	case FilterLogical:
+		return FilterReferences(e.Left, field) || FilterReferences(e.Right, field)
+	case FilterNot:
+		return FilterReferences(e.Expr, field)
+	case FilterComparison:
+		return e.Field == field
+	}
+	return false
+}
+
+// ParseFilter parses and type-checks a filter expression.
+func ParseFilter(input string) (FilterExpr, error) {
+	if len(input) > MaxFilterLength {
+		return nil, fmt.Errorf("invalid filter: expression is longer than %d characters", MaxFilterLength)
+	}
+
+	tokens, err := lexFilter(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := filterParser{tokens: tokens}
+	expr, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorAt(tok, "unexpected token")
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type filterToken struct {
+	kind tokenKind
+	// text is the token as written, value the unquoted contents of a string
+	text  string
+	value string
+	pos   int
+}
+
+func lexFilter(input string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, filterToken{kind: tokenLParen, text: "(", pos: start + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, filterToken{kind: tokenRParen, text: ")", pos: start + 1})
+			i++
+		case r == ',':
+			tokens = append(tokens, filterToken{kind: tokenComma, text: ",", pos: start + 1})
+			i++
+		case r == '"':
+			var value strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, &FilterError{Pos: start + 1, Token: string(runes[start:]), Message: "unterminated string"}
+				}
+				if runes[i] == '"' {
+					i++
+					break
+				}
+				if runes[i] == '\\' {
+					if i+1 >= len(runes) || (runes[i+1] != '"' && runes[i+1] != '\\') {
+						return nil, &FilterError{Pos: i + 1, Token: string(runes[i:min(i+2, len(runes))]), Message: "invalid escape sequence"}
+					}
+					i++
+				}
+				value.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: string(runes[start:i]), value: value.String(), pos: start + 1})
+		case r == '-' || r == '.' || unicode.IsDigit(r):
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenNumber, text: string(runes[start:i]), pos: start + 1})
+		case r == '_' || unicode.IsLetter(r):
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: tokenIdent, text: string(runes[start:i]), pos: start + 1})
+		default:
+			return nil, &FilterError{Pos: start + 1, Token: string(r), Message: "unexpected character"}
+		}
+	}
+
+	return append(tokens, filterToken{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	next   int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) advance() filterToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+// isKeyword reports whether tok is the given keyword, ignoring case.
+func (p *filterParser) isKeyword(tok filterToken, keyword string) bool {
+	return tok.kind == tokenIdent && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *filterParser) errorAt(tok filterToken, message string) error {
+	return &FilterError{Pos: tok.pos, Token: tok.text, Message: message}
+}
+
+func (p *filterParser) parseOr(depth int) (FilterExpr, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(p.peek(), "or") {
+		p.advance()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = FilterLogical{Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd(depth int) (FilterExpr, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(p.peek(), "and") {
+		p.advance()
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = FilterLogical{Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary(depth int) (FilterExpr, error) {
+	tok := p.peek()
+	if depth >= MaxFilterDepth {
+		return nil, p.errorAt(tok, fmt.Sprintf("expression is nested more than %d levels deep", MaxFilterDepth))
+	}
+
+	switch {
+	case p.isKeyword(tok, "not"):
+		p.advance()
+		expr, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return FilterNot{Expr: expr}, nil
+	case tok.kind == tokenLParen:
+		p.advance()
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing.kind != tokenRParen {
+			return nil, p.errorAt(closing, "expected )")
+		}
+		p.advance()
+		return expr, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *filterParser) parseComparison() (FilterExpr, error) {
+	fieldTok := p.advance()
+	if fieldTok.kind != tokenIdent {
+		return nil, p.errorAt(fieldTok, "expected a field name")
+	}
+	fieldType, ok := filterFields[fieldTok.text]
+	if !ok {
+		return nil, p.errorAt(fieldTok, "unknown field")
+	}
+
+	opTok := p.advance()
+	op := strings.ToLower(opTok.text)
+	if _, ok := filterOperators[op]; opTok.kind != tokenIdent || !ok {
+		return nil, p.errorAt(opTok, "expected an operator (eq, ne, gt, ge, lt, le, contains, in)")
+	}
+	if op == "contains" && fieldType != filterString {
+		return nil, p.errorAt(opTok, fmt.Sprintf("contains can't be used on %s field %s", fieldType, fieldTok.text))
+	}
+
+	comparison := FilterComparison{Field: fieldTok.text, Op: op}
+
+	if op != "in" {
+		value, err := p.parseLiteral(fieldTok.text, fieldType)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Values = []interface{}{value}
+		return comparison, nil
+	}
+
+	if open := p.advance(); open.kind != tokenLParen {
+		return nil, p.errorAt(open, "expected ( after in")
+	}
+	for {
+		value, err := p.parseLiteral(fieldTok.text, fieldType)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Values = append(comparison.Values, value)
+
+		tok := p.advance()
+		if tok.kind == tokenRParen {
+			return comparison, nil
+		}
+		if tok.kind != tokenComma {
+			return nil, p.errorAt(tok, "expected , or )")
+		}
+	}
+}
+
+// parseLiteral reads a value and converts it to the type of the field it is compared with.
+func (p *filterParser) parseLiteral(field string, fieldType filterFieldType) (interface{}, error) {
+	tok := p.advance()
+	mismatch := func() error {
+		return p.errorAt(tok, fmt.Sprintf("expected a %s value for %s", fieldType, field))
+	}
+
+	switch fieldType {
+	case filterString:
+		if tok.kind != tokenString {
+			return nil, mismatch()
+		}
+		return tok.value, nil
+	case filterInteger:
+		if tok.kind != tokenNumber {
+			return nil, mismatch()
+		}
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, mismatch()
+		}
+		return n, nil
+	case filterNumber:
+		if tok.kind != tokenNumber {
+			return nil, mismatch()
+		}
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, mismatch()
+		}
+		return n, nil
+	case filterTime:
+		if tok.kind != tokenString {
+			return nil, mismatch()
+		}
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if t, err := time.Parse(layout, tok.value); err == nil {
+				return t, nil
+			}
+		}
+		return nil, p.errorAt(tok, fmt.Sprintf("expected an RFC 3339 timestamp or a date for %s", field))
+	}
+	return nil, mismatch()
+}
+
+// escapeLike escapes the LIKE wildcards in s so that it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{"single comparison", `price gt 10`, "(price > ?)", []interface{}{10.0}},
+		{"and binds tighter than or", `price gt 10 and category eq "toys" or quantity lt 5`,
+			"(((price > ?) AND (category = ?)) OR (quantity < ?))", []interface{}{10.0, "toys", int64(5)}},
+		{"parentheses", `price gt 10 and (category eq "toys" or quantity lt 5)`,
+			"((price > ?) AND ((category = ?) OR (quantity < ?)))", []interface{}{10.0, "toys", int64(5)}},
+		{"not", `not status eq "draft"`, "NOT (status = ?)", []interface{}{"draft"}},
+		{"keywords ignore case", `price GE 1.5 AND sku NE "a"`, "((price >= ?) AND (sku <> ?))", []interface{}{1.5, "a"}},
+		{"in", `category in ("toys", "games")`, "(category IN ?)", []interface{}{[]interface{}{"toys", "games"}}},
+		{"contains escapes wildcards", `name contains "50%_\"off\""`, "(name ILIKE ?)", []interface{}{`%50\%\_"off"%`}},
+		{"negative number", `quantity le -1`, "(quantity <= ?)", []interface{}{int64(-1)}},
+		{"date", `created_at ge "2024-01-31"`, "(created_at >= ?)", []interface{}{time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseFilter(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			sql, args := expr.SQL()
+			if sql != tt.wantSQL {
+				t.Errorf("SQL incorrect. got %s, want %s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args incorrect. got %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestFilterReferences(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  bool
+	}{
+		{`status eq "draft"`, true},
+		{`price gt 10 and (quantity lt 5 or not status in ("draft", "archived"))`, true},
+		{`price gt 10 or name contains "status"`, false},
+	}
+	for _, tt := range tests {
+		expr, err := ParseFilter(tt.input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := FilterReferences(expr, "status"); got != tt.want {
+			t.Errorf("FilterReferences(%s) incorrect. got %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	var tests = []struct {
+		name      string
+		input     string
+		wantPos   int
+		wantToken string
+	}{
+		{"unknown field", `price gt 10 and secret eq "x"`, 17, "secret"},
+		{"unknown operator", `price is 10`, 7, "is"},
+		{"symbol operator", `price > 10`, 7, ">"},
+		{"string for number field", `price gt "10"`, 10, `"10"`},
+		{"number for string field", `category eq 10`, 13, "10"},
+		{"fraction for integer field", `quantity eq 1.5`, 13, "1.5"},
+		{"contains on number field", `price contains "1"`, 7, "contains"},
+		{"missing closing parenthesis", `(price gt 10`, 13, ""},
+		{"trailing token", `price gt 10 10`, 13, "10"},
+		{"dangling and", `price gt 10 and`, 16, ""},
+		{"unterminated string", `name eq "abc`, 9, `"abc`},
+		{"invalid escape", `name eq "a\nb"`, 11, `\n`},
+		{"bad timestamp", `created_at gt "yesterday"`, 15, `"yesterday"`},
+		{"in without list", `category in "toys"`, 13, `"toys"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilter(tt.input)
+			var filterErr *FilterError
+			if !errors.As(err, &filterErr) {
+				t.Fatalf("expected a *FilterError, got %v", err)
+			}
+			if filterErr.Pos != tt.wantPos || filterErr.Token != tt.wantToken {
+				t.Errorf("error location incorrect. got %d %q, want %d %q", filterErr.Pos, filterErr.Token, tt.wantPos, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestParseFilterLimits(t *testing.T) {
+	deep := ""
+	for i := 0; i < MaxFilterDepth+1; i++ {
+		deep += "("
+	}
+	if _, err := ParseFilter(deep + "price gt 1"); err == nil {
+		t.Error("deeply nested filter was accepted")
+	}
+
+	long := "name eq \"" + string(make([]byte, MaxFilterLength)) + "\""
+	if _, err := ParseFilter(long); err == nil {
+		t.Error("overlong filter was accepted")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// IsValidGTIN reports whether code is a GTIN-8, GTIN-12 (UPC-A), GTIN-13 (EAN) or GTIN-14 with a
+// correct check digit.
+func IsValidGTIN(code string) bool {
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		c := code[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		// weights alternate 3, 1, 3, ... starting next to the check digit
+		if (len(code)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	check := code[len(code)-1]
+	if check < '0' || check > '9' {
+		return false
+	}
+	return int(check-'0') == (10-sum%10)%10
+}
+
+// NormalizeGTIN trims whitespace and widens UPC-A codes to GTIN-13, so that a product scanned
+// as UPC-A or EAN-13 is stored and looked up under the same barcode.
+func NormalizeGTIN(code string) string {
+	code = strings.TrimSpace(code)
+	if len(code) == 12 {
+		return "0" + code
+	}
+	return code
+}
+
+func validateGTIN(fl validator.FieldLevel) bool {
+	return IsValidGTIN(strings.TrimSpace(fl.Field().String()))
+}
+
+// NewValidator returns a validator with the custom rules used by the request types registered.
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	if err := v.RegisterValidation("gtin", validateGTIN); err != nil {
+		panic(err)
+	}
+	return v
+}
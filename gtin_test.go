@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestIsValidGTIN(t *testing.T) {
+	var tests = []struct {
+		name  string
+		code  string
+		valid bool
+	}{
+		{"GTIN-8", "96385074", true},
+		{"UPC-A", "036000291452", true},
+		{"EAN-13", "4006381333931", true},
+		{"GTIN-14", "10012345678902", true},
+		{"wrong check digit", "4006381333932", false},
+		{"wrong length", "12345678905", false},
+		{"letters", "40063813339a1", false},
+		{"letter check digit", "400638133393X", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidGTIN(tt.code); got != tt.valid {
+				t.Errorf("validity incorrect. got %v, want %v", got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestNormalizeGTIN(t *testing.T) {
+	var tests = []struct {
+		name string
+		code string
+		want string
+	}{
+		{"UPC-A widened", "036000291452", "0036000291452"},
+		{"EAN-13 unchanged", "4006381333931", "4006381333931"},
+		{"GTIN-8 unchanged", "96385074", "96385074"},
+		{"whitespace trimmed", " 036000291452 ", "0036000291452"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeGTIN(tt.code); got != tt.want {
+				t.Errorf("normalized code incorrect. got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGTINValidationRule(t *testing.T) {
+	v := NewValidator()
+
+	valid := ProductUpdateRequest{Barcode: strPtr("4006381333931")}
+	if err := v.Struct(valid); err != nil {
+		t.Errorf("valid barcode failed validation: %v", err)
+	}
+
+	cleared := ProductUpdateRequest{Barcode: strPtr("")}
+	if err := v.Struct(cleared); err != nil {
+		t.Errorf("empty barcode failed validation: %v", err)
+	}
+
+	invalid := ProductUpdateRequest{Barcode: strPtr("4006381333932")}
+	if err := v.Struct(invalid); err == nil {
+		t.Error("invalid barcode passed validation")
+	}
+}
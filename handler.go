@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
@@ -16,87 +19,126 @@ import (
 type ProductHandler struct {
 	productService *ProductService
 	validator      *validator.Validate
+	pagination     PaginationConfig
 }
 
-func NewProductHandler(service *ProductService, validator *validator.Validate) *ProductHandler {
-	return &ProductHandler{productService: service, validator: validator}
+func NewProductHandler(service *ProductService, validator *validator.Validate, pagination PaginationConfig) *ProductHandler {
+	return &ProductHandler{productService: service, validator: validator, pagination: pagination}
 }
 
 func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
-	zap.L().Info("Create product")
-
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		zap.L().Error("Failed to create product because request body could not be read", zap.Error(err))
-		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
-		return
-	}
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Create product")
 
 	var request ProductCreateRequest
-	err = json.Unmarshal(body, &request)
+	err := decodeJSON(r.Body, &request)
 	if err != nil {
-		zap.L().Info("Failed to create product because request could not be unmarshalled", zap.Error(err))
-		http.Error(w, "failed to unmarshal request body", http.StatusBadRequest)
+		writeBodyError(w, r, "create product", err)
 		return
 	}
 
 	err = h.validator.Struct(request)
 	if err != nil {
 		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			zap.L().Info("Failed to create product because request failed validation", zap.Any("validationErrors", validationErrors))
+			logger.Info("Failed to create product because request failed validation", zap.Any("validationErrors", validationErrors))
 			httpBadRequest(w, formatValidationErrors(validationErrors))
 			return
 		}
-		zap.L().Error("Unexpected error occurred during ProductCreateRequest validation", zap.Error(err))
+		logger.Error("Unexpected error occurred during ProductCreateRequest validation", zap.Error(err))
 		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
 		return
 	}
 
-	product, err := h.productService.CreateProduct(request)
+	product, err := h.productService.CreateProduct(r.Context(), request)
 	if err != nil {
-		if errors.Is(err, ErrDuplicateSKU) {
-			zap.L().Info("Failed to create product", zap.Error(err))
-			http.Error(w, err.Error(), http.StatusConflict)
-			return
-		}
-		zap.L().Error("Failed to create product", zap.Error(err))
-		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		handleProductError(w, r, "create product", err)
 		return
 	}
 
-	zap.L().Info("Product created successfully", zap.Uint("product ID", product.ID))
+	logger.Info("Product created successfully", zap.Uint("product ID", product.ID))
 	httpCreated(w, &product)
 }
 
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
-	zap.L().Info("Get product", zap.String("path", r.URL.Path))
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get product", zap.String("path", r.URL.Path))
 
-	params := mux.Vars(r)
-	id, err := strconv.Atoi(params["id"])
+	id, ok := h.productIDFromPath(w, r, "get product")
+	if !ok {
+		return
+	}
+
+	opts, err := parseReadOptions(r.URL.Query())
 	if err != nil {
-		zap.L().Info("Failed to get product because product ID was invalid", zap.String("path", r.URL.Path))
-		http.Error(w, "invalid product ID", http.StatusBadRequest)
+		logger.Info("Failed to get product because fields or expand param was invalid", zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	fields := opts.Fields
 
-	product, err := h.productService.GetProduct(id)
+	product, err := h.productService.GetProductWithOptions(r.Context(), id, opts)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
-			zap.L().Info("Failed to retrieve product because product was not found", zap.Int("product ID", id))
+			logger.Info("Failed to retrieve product because product was not found", zap.Int("product ID", id))
 			http.Error(w, "product not found", http.StatusNotFound)
 			return
 		}
-		zap.L().Error("Failed to retrieve product", zap.Int("product ID", id), zap.Error(err))
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to retrieve product", zap.Int("product ID", id), zap.Error(err))
+		http.Error(w, "failed to retrieve product", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Product retrieved successfully", zap.Int("product ID", id))
+	if fields == nil {
+		httpOK(w, product)
+		return
+	}
+
+	projected, err := fields.Project(product)
+	if err != nil {
+		logger.Error("Failed to project product", zap.Int("product ID", id), zap.Error(err))
 		http.Error(w, "failed to retrieve product", http.StatusInternalServerError)
 		return
 	}
+	httpOK(w, projected)
+}
+
+func (h *ProductHandler) GetProductByBarcode(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get product by barcode", zap.String("path", r.URL.Path))
+
+	code := mux.Vars(r)["code"]
 
-	zap.L().Info("Product retrieved successfully", zap.Int("product ID", id))
+	product, err := h.productService.GetProductByBarcode(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidBarcode) {
+			logger.Info("Failed to retrieve product because barcode was invalid", zap.String("barcode", code))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, ErrNotFound) {
+			logger.Info("Failed to retrieve product because product was not found", zap.String("barcode", code))
+			http.Error(w, "product not found", http.StatusNotFound)
+			return
+		}
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to retrieve product", zap.String("barcode", code), zap.Error(err))
+		http.Error(w, "failed to retrieve product", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Product retrieved successfully", zap.Uint("product ID", product.ID))
 	httpOK(w, product)
 }
 
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	zap.L().Info("Get products", zap.String("path", r.URL.Path))
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get products", zap.String("path", r.URL.Path))
 
 	var page, size *int
 
@@ -105,7 +147,7 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	if pageStr != "" {
 		pageInt, err := strconv.Atoi(pageStr)
 		if err != nil || pageInt <= 0 {
-			zap.L().Info("Failed to get products because page param was invalid", zap.String("path", r.URL.Path))
+			logger.Info("Failed to get products because page param was invalid", zap.String("path", r.URL.Path))
 			http.Error(w, "invalid page param", http.StatusBadRequest)
 			return
 		}
@@ -116,120 +158,565 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	if sizeStr != "" {
 		sizeInt, err := strconv.Atoi(sizeStr)
 		if err != nil || sizeInt <= 0 {
-			zap.L().Info("Failed to get products because size param was invalid", zap.String("path", r.URL.Path))
+			logger.Info("Failed to get products because size param was invalid", zap.String("path", r.URL.Path))
 			http.Error(w, "invalid size param", http.StatusBadRequest)
 			return
 		}
+		if sizeInt > h.pagination.MaxSize {
+			logger.Info("Failed to get products because size param was too large", zap.String("path", r.URL.Path), zap.Int("size", sizeInt))
+			http.Error(w, fmt.Sprintf("size param must not exceed %d", h.pagination.MaxSize), http.StatusBadRequest)
+			return
+		}
 		size = &sizeInt
 	}
 
 	if page != nil && size == nil {
-		zap.L().Info("Failed to get products because page was specified but size wasn't", zap.String("path", r.URL.Path))
+		logger.Info("Failed to get products because page was specified but size wasn't", zap.String("path", r.URL.Path))
 		http.Error(w, "must specify size if page is included", http.StatusBadRequest)
 		return
 	}
+	if size == nil {
+		defaultSize := h.pagination.DefaultSize
+		size = &defaultSize
+	}
+
+	filter, err := parseProductFilter(r.URL.Query())
+	if err != nil {
+		logger.Info("Failed to get products because filter params were invalid", zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	response, err := h.productService.GetProducts(page, size)
+	opts, err := parseReadOptions(r.URL.Query())
+	if err != nil {
+		logger.Info("Failed to get products because fields or expand param was invalid", zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields := opts.Fields
+
+	response, err := h.productService.GetProducts(r.Context(), page, size, filter, opts)
 	if err != nil {
 		if errors.Is(err, ErrOutOfRange) {
-			zap.L().Info("Failed to get products", zap.Error(err))
+			logger.Info("Failed to get products", zap.Error(err))
 			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 			return
 		}
-		zap.L().Error("Failed to get products", zap.Error(err))
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to get products", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	if fields == nil {
+		httpOK(w, response)
+		return
+	}
+
+	projected := make([]map[string]json.RawMessage, len(response.Products))
+	for i := range response.Products {
+		projected[i], err = fields.Project(&response.Products[i])
+		if err != nil {
+			logger.Error("Failed to project products", zap.Error(err))
+			http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// the outer products field shadows the one of the embedded response
+	httpOK(w, struct {
+		*BulkProductResponse
+		Products []map[string]json.RawMessage `json:"products"`
+	}{response, projected})
+}
+
+// LookupProducts returns the products matching a batch of SKUs and ids, together with the
+// entries that didn't match, in a single round-trip.
+func (h *ProductHandler) LookupProducts(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Lookup products")
+
+	var request ProductLookupRequest
+	if !decodeAndValidate(w, r, h.validator, &request, "look up products") {
+		return
+	}
+
+	response, err := h.productService.LookupProducts(r.Context(), request)
+	if err != nil {
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to look up products", zap.Error(err))
 		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
 		return
 	}
 
+	logger.Info("Products looked up successfully", zap.Int("found", len(response.Products)),
+		zap.Int("missing", len(response.MissingSKUs)+len(response.MissingIDs)))
 	httpOK(w, response)
 }
 
-func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
-	zap.L().Info("Update product", zap.String("path", r.URL.Path))
+func (h *ProductHandler) TransitionProduct(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Transition product", zap.String("path", r.URL.Path))
 
 	params := mux.Vars(r)
 	id, err := strconv.Atoi(params["id"])
 	if err != nil {
-		zap.L().Info("Failed to update product because product ID was invalid", zap.String("path", r.URL.Path))
+		logger.Info("Failed to transition product because product ID was invalid", zap.String("path", r.URL.Path))
 		http.Error(w, "invalid product ID", http.StatusBadRequest)
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
+	to, ok := transitionTargets[params["transition"]]
+	if !ok {
+		logger.Info("Failed to transition product because transition was unknown", zap.String("path", r.URL.Path))
+		http.Error(w, "unknown transition", http.StatusNotFound)
+		return
+	}
+
+	product, err := h.productService.TransitionProduct(r.Context(), id, to)
 	if err != nil {
-		zap.L().Error("Failed to update product because request body could not be read", zap.Error(err))
+		var transitionError *TransitionError
+		if errors.As(err, &transitionError) {
+			logger.Info("Failed to transition product", zap.Error(err))
+			httpBadRequest(w, map[string]string{"status": transitionError.Error()})
+			return
+		}
+		if errors.Is(err, ErrNotFound) {
+			logger.Info("Failed to transition product", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to transition product", zap.Error(err))
 		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
 		return
 	}
 
+	logger.Info("Product transitioned successfully", zap.Uint("product ID", product.ID), zap.String("status", product.Status))
+	httpOK(w, product)
+}
+
+// GetProductStats returns aggregate statistics for the products matching the list filters.
+func (h *ProductHandler) GetProductStats(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get product stats", zap.String("path", r.URL.Path))
+
+	filter, err := parseProductFilter(r.URL.Query())
+	if err != nil {
+		logger.Info("Failed to get product stats because filter params were invalid", zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.productService.GetProductStats(r.Context(), filter)
+	if err != nil {
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to get product stats", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	httpOK(w, stats)
+}
+
+func (h *ProductHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get tags", zap.String("path", r.URL.Path))
+
+	tags, err := h.productService.GetTags(r.Context())
+	if err != nil {
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to get tags", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	httpOK(w, tags)
+}
+
+func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Update product", zap.String("path", r.URL.Path))
+
+	id, ok := h.productIDFromPath(w, r, "update product")
+	if !ok {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == MediaTypeMergePatch || mediaType == MediaTypeJSONPatch {
+		h.patchProduct(w, r, id, mediaType)
+		return
+	}
+
 	var request ProductUpdateRequest
-	err = json.Unmarshal(body, &request)
+	err := decodeJSON(r.Body, &request)
 	if err != nil {
-		zap.L().Info("Failed to update product because request could not be unmarshalled", zap.Error(err))
-		http.Error(w, "failed to unmarshal request body", http.StatusBadRequest)
+		writeBodyError(w, r, "update product", err)
 		return
 	}
 
 	err = h.validator.Struct(request)
 	if err != nil {
 		if validationErrors, ok := err.(validator.ValidationErrors); ok {
-			zap.L().Info("Failed to update product because request failed validation", zap.Any("validationErrors", validationErrors))
+			logger.Info("Failed to update product because request failed validation", zap.Any("validationErrors", validationErrors))
 			httpBadRequest(w, formatValidationErrors(validationErrors))
 			return
 		}
-		zap.L().Error("Unexpected error occurred during ProductUpdateRequest validation", zap.Error(err))
+		logger.Error("Unexpected error occurred during ProductUpdateRequest validation", zap.Error(err))
 		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
 		return
 	}
 
-	product, err := h.productService.UpdateProduct(id, request)
+	product, err := h.productService.UpdateProduct(r.Context(), id, request)
+	if err != nil {
+		handleProductError(w, r, "update product", err)
+		return
+	}
+
+	logger.Info("Product updated successfully", zap.Uint("product ID", product.ID))
+	httpOK(w, &product)
+}
+
+// patchProduct applies a JSON Merge Patch or JSON Patch body to a product. The patched product
+// must pass the same validation as a newly created one.
+func (h *ProductHandler) patchProduct(w http.ResponseWriter, r *http.Request, id int, mediaType string) {
+	logger := LoggerFromContext(r.Context())
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeBodyError(w, r, "patch product", err)
+		return
+	}
+
+	product, err := h.productService.PatchProduct(r.Context(), id, func(doc ProductCreateRequest) (ProductCreateRequest, error) {
+		request, err := ApplyProductPatch(mediaType, doc, body)
+		if err != nil {
+			return request, err
+		}
+		if request.Status != "" {
+			return request, fmt.Errorf("%w: status is changed through the transition endpoints", ErrInvalidPatch)
+		}
+		return request, h.validator.Struct(request)
+	})
 	if err != nil {
-		if errors.Is(err, ErrDuplicateSKU) {
-			zap.L().Info("Failed to update product", zap.Error(err))
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrors):
+			logger.Info("Failed to patch product because patched product failed validation", zap.Any("validationErrors", validationErrors))
+			httpBadRequest(w, formatValidationErrors(validationErrors))
+		case errors.Is(err, ErrInvalidPatch):
+			logger.Info("Failed to patch product because patch was invalid", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrPatchTestFailed):
+			logger.Info("Failed to patch product because a test operation failed", zap.Error(err))
 			http.Error(w, err.Error(), http.StatusConflict)
-			return
+		default:
+			handleProductError(w, r, "patch product", err)
 		}
-		if errors.Is(err, ErrNotFound) {
-			zap.L().Info("Failed to update product", zap.Error(err))
-			http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	logger.Info("Product patched successfully", zap.Uint("product ID", product.ID), zap.String("media type", mediaType))
+	httpOK(w, product)
+}
+
+// ReplaceProduct overwrites a product with the complete document in the body. Unlike PATCH,
+// fields left out of the body are reset rather than kept.
+func (h *ProductHandler) ReplaceProduct(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Replace product", zap.String("path", r.URL.Path))
+
+	id, ok := h.productIDFromPath(w, r, "replace product")
+	if !ok {
+		return
+	}
+
+	var request ProductCreateRequest
+	if !decodeAndValidate(w, r, h.validator, &request, "replace product") {
+		return
+	}
+	if request.Status != "" {
+		logger.Info("Failed to replace product because the body set a status", zap.String("status", request.Status))
+		httpBadRequest(w, map[string]string{"Status": "is changed through POST /api/v1/products/{id}/{transition}"})
+		return
+	}
+
+	product, err := h.productService.ReplaceProduct(r.Context(), id, request)
+	if err != nil {
+		handleProductError(w, r, "replace product", err)
+		return
+	}
+
+	logger.Info("Product replaced successfully", zap.Uint("product ID", product.ID))
+	httpOK(w, product)
+}
+
+// PutProductBySKU creates or fully replaces the product with the SKU in the path, responding
+// with 201 or 200 respectively. The SKU may be left out of the body but must match if given.
+func (h *ProductHandler) PutProductBySKU(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Put product by SKU", zap.String("path", r.URL.Path))
+
+	sku := mux.Vars(r)["sku"]
+
+	var request ProductCreateRequest
+	err := decodeJSON(r.Body, &request)
+	if err != nil {
+		writeBodyError(w, r, "put product", err)
+		return
+	}
+
+	if request.SKU == "" {
+		request.SKU = sku
+	}
+	if request.SKU != sku {
+		logger.Info("Failed to put product because SKU in body did not match path", zap.String("sku", sku), zap.String("body sku", request.SKU))
+		httpBadRequest(w, map[string]string{"SKU": "must match the SKU in the path"})
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			logger.Info("Failed to put product because request failed validation", zap.Any("validationErrors", validationErrors))
+			httpBadRequest(w, formatValidationErrors(validationErrors))
 			return
 		}
-		zap.L().Error("Failed to update product", zap.Error(err))
+		logger.Error("Unexpected error occurred during ProductCreateRequest validation", zap.Error(err))
 		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
 		return
 	}
 
-	zap.L().Info("Product updated successfully", zap.Uint("product ID", product.ID))
-	httpOK(w, &product)
+	product, created, err := h.productService.UpsertProductBySKU(r.Context(), sku, request)
+	if err != nil {
+		handleProductError(w, r, "put product", err)
+		return
+	}
+
+	logger.Info("Product put successfully", zap.Uint("product ID", product.ID), zap.Bool("created", created))
+	if created {
+		httpCreated(w, product)
+		return
+	}
+	httpOK(w, product)
 }
 
 func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
-	zap.L().Info("Delete product", zap.String("path", r.URL.Path))
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Delete product", zap.String("path", r.URL.Path))
 
-	params := mux.Vars(r)
-	id, err := strconv.Atoi(params["id"])
-	if err != nil {
-		zap.L().Info("Failed to delete product because product ID was invalid", zap.String("path", r.URL.Path))
-		http.Error(w, "invalid product ID", http.StatusBadRequest)
+	id, ok := h.productIDFromPath(w, r, "delete product")
+	if !ok {
 		return
 	}
 
-	err = h.productService.DeleteProduct(id)
+	var err error
+	purge := r.URL.Query().Get("purge") == "true"
+	if purge {
+		err = h.productService.PurgeProduct(r.Context(), id)
+	} else {
+		err = h.productService.DeleteProduct(r.Context(), id)
+	}
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
-			zap.L().Info("Failed to delete product because product was not found", zap.Int("product ID", id))
+			logger.Info("Failed to delete product because product was not found", zap.Int("product ID", id))
 			http.Error(w, "invalid product ID", http.StatusNotFound)
 			return
 		}
-		zap.L().Error("Failed to delete product", zap.Error(err))
+		if errors.Is(err, ErrProductInBundle) {
+			logger.Info("Failed to delete product because it is part of a bundle", zap.Int("product ID", id))
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to delete product", zap.Error(err))
 		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
 		return
 	}
 
-	zap.L().Info("Product deleted successfully", zap.Int("product ID", id))
+	logger.Info("Product deleted successfully", zap.Int("product ID", id), zap.Bool("purged", purge))
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// productIDFromPath resolves the product addressed by the request path, either by its numeric
+// id or, on the /products/sku/{sku} routes, by the SKU of a non-deleted product. It writes an
+// error response and returns false if the product can't be resolved.
+func (h *ProductHandler) productIDFromPath(w http.ResponseWriter, r *http.Request, action string) (int, bool) {
+	logger := LoggerFromContext(r.Context())
+	params := mux.Vars(r)
+
+	sku, bySKU := params["sku"]
+	if !bySKU {
+		id, err := strconv.Atoi(params["id"])
+		if err != nil {
+			logger.Info("Failed to "+action+" because product ID was invalid", zap.String("path", r.URL.Path))
+			http.Error(w, "invalid product ID", http.StatusBadRequest)
+			return 0, false
+		}
+		return id, true
+	}
+
+	id, err := h.productService.GetProductIDBySKU(r.Context(), sku)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			logger.Info("Failed to "+action+" because product was not found", zap.String("sku", sku))
+			http.Error(w, "product not found", http.StatusNotFound)
+			return 0, false
+		}
+		if writeContextError(w, r, err) {
+			return 0, false
+		}
+		logger.Error("Failed to "+action+" because SKU could not be resolved", zap.String("sku", sku), zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return 0, false
+	}
+	return id, true
+}
+
+// handleProductError writes the response for an error returned while creating or changing a
+// product.
+func handleProductError(w http.ResponseWriter, r *http.Request, action string, err error) {
+	logger := LoggerFromContext(r.Context())
+	var attributeErrors AttributeErrors
+	switch {
+	case errors.As(err, &attributeErrors):
+		logger.Info("Failed to "+action+" because attributes failed validation", zap.Error(err))
+		httpBadRequest(w, attributeErrors)
+	case errors.Is(err, ErrInvalidBundle):
+		logger.Info("Failed to "+action+" because bundle was invalid", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrDuplicateSKU), errors.Is(err, ErrDuplicateBarcode):
+		logger.Info("Failed to "+action, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrNotFound):
+		logger.Info("Failed to "+action, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to "+action, zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+	}
+}
+
+// parseProductFilter reads the list filters from query. Attribute filters take the form
+// attr.<name>=<value>; tag filters are comma-separated lists in tags_any and tags_all; filter
+// holds an expression in the grammar of ParseFilter.
+// Only active products are listed unless status names other statuses or is "all".
+func parseProductFilter(query url.Values) (ProductFilter, error) {
+	filter := ProductFilter{
+		Attributes: map[string]string{},
+		TagsAny:    NormalizeTags(splitQueryList(query.Get("tags_any"))),
+		TagsAll:    NormalizeTags(splitQueryList(query.Get("tags_all"))),
+		Statuses:   []string{StatusActive},
+	}
+
+	switch status := query.Get("status"); status {
+	case "":
+	case "all":
+		filter.Statuses = nil
+	default:
+		filter.Statuses = splitQueryList(status)
+		for _, s := range filter.Statuses {
+			if !IsValidStatus(s) {
+				return filter, fmt.Errorf("invalid status %q", s)
+			}
+		}
+	}
+
+	if expression := query.Get("filter"); expression != "" {
+		parsed, err := ParseFilter(expression)
+		if err != nil {
+			return filter, err
+		}
+		filter.Expression = parsed
+		// An expression on status replaces the default of active products only.
+		if query.Get("status") == "" && FilterReferences(parsed, "status") {
+			filter.Statuses = nil
+		}
+	}
+
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, "attr.")
+		if !ok {
+			continue
+		}
+		if !attributeNamePattern.MatchString(name) {
+			return filter, fmt.Errorf("invalid attribute filter %q", key)
+		}
+		if len(values) != 1 {
+			return filter, fmt.Errorf("attribute filter %q must be given exactly once", key)
+		}
+		filter.Attributes[name] = values[0]
+	}
+
+	return filter, nil
+}
+
+// parseReadOptions reads the fields and expand params. Expanded relations are always returned,
+// even if fields doesn't name them, and fields may only name relations that are expanded.
+func parseReadOptions(query url.Values) (ProductReadOptions, error) {
+	fields, err := ParseFieldSet(query.Get("fields"))
+	if err != nil {
+		return ProductReadOptions{}, err
+	}
+	expand, err := ParseExpansion(query.Get("expand"))
+	if err != nil {
+		return ProductReadOptions{}, err
+	}
+	for _, relation := range sortedKeys(expandRelations) {
+		if fields != nil && fields.Has(relation) && !containsString(expand, relation) {
+			return ProductReadOptions{}, fmt.Errorf("field %q is only available with expand=%s", relation, relation)
+		}
+	}
+	return ProductReadOptions{Fields: fields.With(expand.Roots()...), Expand: expand}, nil
+}
+
+func splitQueryList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// decodeAndValidate strictly decodes the JSON body into request and validates it, writing an error
+// response and returning false on failure.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, validate *validator.Validate, request interface{}, action string) bool {
+	logger := LoggerFromContext(r.Context())
+	err := decodeJSON(r.Body, request)
+	if err != nil {
+		writeBodyError(w, r, action, err)
+		return false
+	}
+
+	err = validate.Struct(request)
+	if err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			logger.Info("Failed to "+action+" because request failed validation", zap.Any("validationErrors", validationErrors))
+			httpBadRequest(w, formatValidationErrors(validationErrors))
+			return false
+		}
+		logger.Error("Unexpected error occurred during request validation", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
 func httpOK(w http.ResponseWriter, body interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -248,6 +735,12 @@ func httpBadRequest(w http.ResponseWriter, body interface{}) {
 	json.NewEncoder(w).Encode(body)
 }
 
+func httpServiceUnavailable(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(body)
+}
+
 func formatValidationErrors(validationErrors validator.ValidationErrors) map[string]string {
 	errorsMap := make(map[string]string)
 	for _, ve := range validationErrors {
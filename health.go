@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	HealthStatusReady        = "ready"
+	HealthStatusNotReady     = "not_ready"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+// HealthHandler answers the liveness and readiness probes. Liveness only shows the process is
+// serving; readiness also requires a reachable database with the schema migrated, and turns
+// false once shutdown starts so traffic moves elsewhere while requests drain.
+type HealthHandler struct {
+	db           *gorm.DB
+	pingTimeout  time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(db *gorm.DB, pingTimeout time.Duration) *HealthHandler {
+	return &HealthHandler{db: db, pingTimeout: pingTimeout}
+}
+
+type ReadinessResponse struct {
+	Status     string          `json:"status"`
+	Database   DatabaseHealth  `json:"database"`
+	Migrations MigrationHealth `json:"migrations"`
+	Pool       PoolStats       `json:"pool"`
+}
+
+type DatabaseHealth struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// MigrationHealth reports whether every table the service uses exists.
+type MigrationHealth struct {
+	Status        string   `json:"status"`
+	MissingTables []string `json:"missing_tables"`
+}
+
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+}
+
+// MarkShuttingDown makes readiness fail from now on.
+func (h *HealthHandler) MarkShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *HealthHandler) Live(w http.ResponseWriter, _ *http.Request) {
+	httpOK(w, map[string]string{"status": "ok"})
+}
+
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	response := h.checkReadiness(r.Context())
+	if response.Status != HealthStatusReady {
+		logger.Warn("Readiness check failed", zap.String("status", response.Status),
+			zap.String("database", response.Database.Status), zap.Strings("missing_tables", response.Migrations.MissingTables))
+		httpServiceUnavailable(w, response)
+		return
+	}
+	httpOK(w, response)
+}
+
+func (h *HealthHandler) checkReadiness(ctx context.Context) ReadinessResponse {
+	response := ReadinessResponse{Status: HealthStatusReady}
+
+	ctx, cancel := context.WithTimeout(ctx, h.pingTimeout)
+	defer cancel()
+
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		response.Status = HealthStatusNotReady
+		response.Database = DatabaseHealth{Status: "down", Error: err.Error()}
+		return response
+	}
+
+	start := time.Now()
+	err = sqlDB.PingContext(ctx)
+	response.Database.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		response.Status = HealthStatusNotReady
+		response.Database.Status = "down"
+		response.Database.Error = err.Error()
+		response.Migrations = MigrationHealth{Status: "unknown", MissingTables: []string{}}
+	} else {
+		response.Database.Status = "up"
+		response.Migrations = h.checkMigrations(ctx)
+		if response.Migrations.Status != "applied" {
+			response.Status = HealthStatusNotReady
+		}
+	}
+
+	stats := sqlDB.Stats()
+	response.Pool = PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+	}
+
+	if h.shuttingDown.Load() {
+		response.Status = HealthStatusShuttingDown
+	}
+	return response
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) MigrationHealth {
+	migrator := h.db.WithContext(ctx).Migrator()
+	migrations := MigrationHealth{Status: "applied", MissingTables: []string{}}
+
+	for _, model := range schemaModels() {
+		stmt := &gorm.Statement{DB: h.db}
+		if err := stmt.Parse(model); err != nil {
+			migrations.Status = "unknown"
+			continue
+		}
+		if !migrator.HasTable(stmt.Schema.Table) {
+			migrations.Status = "pending"
+			migrations.MissingTables = append(migrations.MissingTables, stmt.Schema.Table)
+		}
+	}
+	if ctx.Err() != nil {
+		migrations.Status = "unknown"
+	}
+	return migrations
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestHealthProbes(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	e.GET("/health/live").Expect().Status(http.StatusOK).JSON().Object().Value("status").IsEqual("ok")
+
+	t.Run("Request IDs", func(t *testing.T) {
+		e.GET("/health/live").WithHeader(RequestIDHeader, "probe-1").Expect().Header(RequestIDHeader).IsEqual("probe-1")
+		e.GET("/no-such-route").Expect().Status(http.StatusNotFound).Header(RequestIDHeader).Length().IsEqual(32)
+		e.DELETE("/health/live").Expect().Status(http.StatusMethodNotAllowed).Header(RequestIDHeader).NotEmpty()
+	})
+
+	ready := e.GET("/health/ready").Expect().Status(http.StatusOK).JSON().Object()
+	ready.Value("status").IsEqual(HealthStatusReady)
+	ready.Path("$.database.status").IsEqual("up")
+	ready.Path("$.migrations.status").IsEqual("applied")
+	ready.Path("$.migrations.missing_tables").Array().IsEmpty()
+	ready.Path("$.pool.open_connections").Number().Ge(1)
+
+	t.Run("Missing table", func(t *testing.T) {
+		if err := db.Migrator().DropTable(&AttributeDefinition{}); err != nil {
+			t.Fatalf("failed to drop table: %v", err)
+		}
+		defer db.AutoMigrate(&AttributeDefinition{})
+
+		notReady := e.GET("/health/ready").Expect().Status(http.StatusServiceUnavailable).JSON().Object()
+		notReady.Value("status").IsEqual(HealthStatusNotReady)
+		notReady.Path("$.migrations.status").IsEqual("pending")
+		notReady.Path("$.migrations.missing_tables").Array().IsEqual([]string{"attribute_definitions"})
+	})
+
+	t.Run("Shutting down", func(t *testing.T) {
+		health := NewHealthHandler(db, time.Second)
+		probe := httptest.NewServer(http.HandlerFunc(health.Ready))
+		defer probe.Close()
+
+		pe := httpexpect.Default(t, probe.URL)
+		pe.GET("/").Expect().Status(http.StatusOK)
+
+		health.MarkShuttingDown()
+		pe.GET("/").Expect().Status(http.StatusServiceUnavailable).JSON().Object().Value("status").IsEqual(HealthStatusShuttingDown)
+		e.GET("/health/live").Expect().Status(http.StatusOK)
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONMap is a JSON object stored in a jsonb column.
+type JSONMap map[string]interface{}
+
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (m *JSONMap) Scan(value interface{}) error {
+	b, err := jsonBytes(value)
+	if err != nil {
+		return err
+	}
+	result := JSONMap{}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &result); err != nil {
+			return err
+		}
+	}
+	*m = result
+	return nil
+}
+
+// StringList is a list of strings stored as a jsonb array.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	b, err := jsonBytes(value)
+	if err != nil {
+		return err
+	}
+	result := StringList{}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &result); err != nil {
+			return err
+		}
+	}
+	*l = result
+	return nil
+}
+
+func jsonBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported type for json column: %T", value)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksRefetchInterval limits how often the keys are fetched outside the refresh interval,
+	// for a token signed with an unknown key or after a failed fetch.
+	jwksRefetchInterval = time.Minute
+	jwksFetchTimeout    = 10 * time.Second
+	jwksMaxBytes        = 1 << 20
+)
+
+// JWKS is the set of public keys SSO tokens are signed with. Keys are read once from a local
+// file, or fetched from a URL when first needed and again every refresh interval.
+type JWKS struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+	fetches singleflight.Group
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	triedAt   time.Time
+	fetchErr  error
+}
+
+// LoadJWKSFile reads a key set from a JSON file.
+func LoadJWKSFile(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file %s: %w", path, err)
+	}
+	return &JWKS{keys: keys}, nil
+}
+
+// NewRemoteJWKS returns a key set fetched from url.
+func NewRemoteJWKS(url string, refresh time.Duration) *JWKS {
+	return &JWKS{url: url, refresh: refresh, client: &http.Client{Timeout: jwksFetchTimeout}}
+}
+
+// Key returns the key with the given ID. A token without a key ID can only be checked when
+// the set holds a single key. Unknown keys are reported as ErrInvalidToken, and keys that
+// can't be fetched as ErrJWKSUnavailable. Stale keys are used while the URL can't be reached.
+func (s *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	var err error
+	if s.url != "" && s.needsFetch(kid) {
+		err = s.refreshKeys(ctx, kid)
+	}
+
+	s.mu.RLock()
+	fetched := s.keys != nil
+	if err == nil {
+		err = s.fetchErr
+	}
+	key, ok := s.lookup(kid)
+	s.mu.RUnlock()
+
+	if !fetched {
+		return nil, fmt.Errorf("%w: %v", ErrJWKSUnavailable, err)
+	}
+	if !ok {
+		if kid == "" {
+			return nil, fmt.Errorf("%w: token has no key ID", ErrInvalidToken)
+		}
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// needsFetch reports whether the keys are missing or stale, or kid is unknown, and no fetch
+// has been tried within the refetch interval. Limiting retries keeps an unreachable URL from
+// holding up every request, and a stream of forged tokens from flooding the SSO.
+func (s *JWKS) needsFetch(kid string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	if now.Sub(s.triedAt) < jwksRefetchInterval {
+		return false
+	}
+	stale := s.keys == nil || now.Sub(s.fetchedAt) >= s.refresh
+	_, known := s.lookup(kid)
+	return stale || !known
+}
+
+// refreshKeys fetches the keys again. Concurrent callers share a single fetch, which runs
+// without the lock held and isn't tied to any one request, so a caller that gives up doesn't
+// cancel it for the others.
+func (s *JWKS) refreshKeys(ctx context.Context, kid string) error {
+	result := s.fetches.DoChan("keys", func() (interface{}, error) {
+		// Callers that waited for a fetch that just finished don't start another one.
+		if !s.needsFetch(kid) {
+			return nil, nil
+		}
+
+		fetchCtx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+		defer cancel()
+		keys, err := s.fetch(fetchCtx)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.triedAt = time.Now()
+		s.fetchErr = err
+		if err != nil {
+			zap.L().Warn("Failed to fetch JWKS", zap.String("url", s.url), zap.Bool("stale keys kept", s.keys != nil), zap.Error(err))
+			return nil, err
+		}
+		s.keys = keys
+		s.fetchedAt = s.triedAt
+		zap.L().Info("JWKS fetched successfully", zap.String("url", s.url), zap.Int("keys", len(keys)))
+		return nil, nil
+	})
+
+	select {
+	case r := <-result:
+		return r.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lookup finds the key with the given ID. The caller must hold s.mu.
+func (s *JWKS) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// fetch returns the keys served at the URL.
+func (s *JWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s returned %s", s.url, response.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(response.Body, jwksMaxBytes))
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS from %s: %w", s.url, err)
+	}
+	return keys, nil
+}
+
+// jsonWebKey holds the members of a JWK (RFC 7517) used for signature keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the signature keys of a key set by ID. Keys of other types or uses are
+// skipped, so a set may also publish keys this service has no use for.
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if errors.Is(err, errUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %d (%q): %w", i, jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no usable signature keys")
+	}
+	return keys, nil
+}
+
+var errUnsupportedKey = errors.New("unsupported key type")
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeKeyParam("n", k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeKeyParam("e", k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var checker ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, checker = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, checker = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, checker = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, errUnsupportedKey
+		}
+		x, err := decodeKeyParam("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeKeyParam("y", k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, errors.New("invalid EC point")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		copy(point[1+size-len(x):], x)
+		copy(point[1+2*size-len(y):], y)
+		if _, err := checker.NewPublicKey(point); err != nil {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errUnsupportedKey
+		}
+		x, err := decodeKeyParam("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, errUnsupportedKey
+	}
+}
+
+func decodeKeyParam(name, value string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid %q parameter", name)
+	}
+	return b, nil
+}
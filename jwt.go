@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenSigningMethods are the algorithms accepted on SSO tokens. Only public-key algorithms
+// are listed, so a token can't be signed with the public key as an HMAC secret or not at all.
+var tokenSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// TokenVerifier validates bearer tokens issued by the SSO and grants their subject the
+// permissions the policy gives to the roles in the token.
+type TokenVerifier struct {
+	keys        *JWKS
+	parser      *jwt.Parser
+	rolesClaim  []string
+	roleMapping map[string]string
+	policy      map[string][]string
+}
+
+// NewTokenVerifier returns a verifier for the SSO configured in cfg, or nil if tokens aren't
+// accepted. A JWKS file is read here so that a bad file stops the service from starting.
+func NewTokenVerifier(cfg AuthConfig) (*TokenVerifier, error) {
+	if !cfg.JWT.Enabled() {
+		return nil, nil
+	}
+
+	var keys *JWKS
+	if cfg.JWT.JWKSFile != "" {
+		var err error
+		if keys, err = LoadJWKSFile(cfg.JWT.JWKSFile); err != nil {
+			return nil, err
+		}
+	} else {
+		keys = NewRemoteJWKS(cfg.JWT.JWKSURL, cfg.JWT.JWKSRefresh)
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(tokenSigningMethods),
+		jwt.WithIssuer(cfg.JWT.Issuer),
+		jwt.WithAudience(cfg.JWT.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.JWT.Leeway),
+	)
+
+	return &TokenVerifier{
+		keys:        keys,
+		parser:      parser,
+		rolesClaim:  strings.Split(cfg.JWT.RolesClaim, "."),
+		roleMapping: cfg.JWT.RoleMapping,
+		policy:      cfg.Policy,
+	}, nil
+}
+
+// Verify checks the signature, issuer, audience and expiry of token and returns its caller.
+// A valid token whose roles grant nothing yields a Principal without scopes.
+func (v *TokenVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		if errors.Is(err, ErrJWKSUnavailable) || errors.Is(err, ErrInvalidToken) {
+			return Principal{}, err
+		}
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+
+	roles := v.roles(claims)
+	return Principal{Subject: subject, Roles: roles, Scopes: v.scopes(roles)}, nil
+}
+
+// roles returns the policy roles named in the roles claim, mapped through the role mapping.
+func (v *TokenVerifier) roles(claims jwt.MapClaims) []string {
+	var values []string
+	switch claim := claimValue(claims, v.rolesClaim).(type) {
+	case string:
+		values = strings.Fields(claim)
+	case []interface{}:
+		for _, value := range claim {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	var roles []string
+	for _, value := range values {
+		role := value
+		if len(v.roleMapping) > 0 {
+			var ok bool
+			if role, ok = v.roleMapping[value]; !ok {
+				continue
+			}
+		}
+		if !containsString(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// scopes returns every permission the policy grants to roles.
+func (v *TokenVerifier) scopes(roles []string) []string {
+	var scopes []string
+	for _, role := range roles {
+		for _, scope := range v.policy[role] {
+			if !containsString(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+// claimValue follows path through nested claim objects, returning nil if it leads nowhere.
+func claimValue(claims map[string]interface{}, path []string) interface{} {
+	var value interface{} = claims
+	for _, name := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
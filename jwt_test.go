@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://sso.example.com"
+	testAudience = "simpler-test"
+)
+
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.Signer
+}
+
+func newSigningKeys(t *testing.T) (rsaKey, ecKey signingKey) {
+	t.Helper()
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	return signingKey{"rsa-1", jwt.SigningMethodRS256, rsaPrivate}, signingKey{"ec-1", jwt.SigningMethodES256, ecPrivate}
+}
+
+// jwksJSON returns the key set publishing the public halves of keys.
+func jwksJSON(t *testing.T, keys ...signingKey) []byte {
+	t.Helper()
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	for _, k := range keys {
+		switch public := k.key.Public().(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, jsonWebKey{Kty: "RSA", Kid: k.kid, Use: "sig", N: encode(public.N.Bytes()), E: encode(big.NewInt(int64(public.E)).Bytes())})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, jsonWebKey{Kty: "EC", Kid: k.kid, Crv: "P-256", X: encode(public.X.FillBytes(make([]byte, 32))), Y: encode(public.Y.FillBytes(make([]byte, 32)))})
+		}
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to encode JWKS: %v", err)
+	}
+	return data
+}
+
+func writeJWKSFile(t *testing.T, keys ...signingKey) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksJSON(t, keys...), 0o600); err != nil {
+		t.Fatalf("failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+// validClaims returns the claims of a token the verifier built by newTestVerifier accepts.
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"sub":   "alice@example.com",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"roles": []string{"editor"},
+	}
+}
+
+func signToken(t *testing.T, key signingKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	signed, err := token.SignedString(key.key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func testAuthConfig(jwksFile string) AuthConfig {
+	cfg := DefaultConfig().Auth
+	cfg.JWT.JWKSFile = jwksFile
+	cfg.JWT.Issuer = testIssuer
+	cfg.JWT.Audience = testAudience
+	return cfg
+}
+
+func newTestVerifier(t *testing.T, cfg AuthConfig) *TokenVerifier {
+	t.Helper()
+	verifier, err := NewTokenVerifier(cfg)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	return verifier
+}
+
+func TestTokenVerifier(t *testing.T) {
+	rsaKey, ecKey := newSigningKeys(t)
+	_, otherKey := newSigningKeys(t)
+	verifier := newTestVerifier(t, testAuthConfig(writeJWKSFile(t, rsaKey, ecKey)))
+
+	with := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		change(claims)
+		return claims
+	}
+	hmacToken := func() string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+		token.Header["kid"] = rsaKey.kid
+		signed, _ := token.SignedString([]byte("secret"))
+		return signed
+	}
+	noneToken := func() string {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims())
+		signed, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		return signed
+	}
+
+	var tests = []struct {
+		name   string
+		token  string
+		scopes []string
+		err    string
+	}{
+		{"RSA", signToken(t, rsaKey, validClaims()), []string{ScopeProductsWrite}, ""},
+		{"EC", signToken(t, ecKey, validClaims()), []string{ScopeProductsWrite}, ""},
+		{"audience list", signToken(t, rsaKey, with(func(c jwt.MapClaims) { c["aud"] = []string{"other", testAudience} })), []string{ScopeProductsWrite}, ""},
+		{"expired within leeway", signToken(t, rsaKey, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-10 * time.Second).Unix() })), []string{ScopeProductsWrite}, ""},
+		{"no known roles", signToken(t, rsaKey, with(func(c jwt.MapClaims) { c["roles"] = []string{"guest"} })), nil, ""},
+		{"expired", signToken(t, rsaKey, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })), nil, "expired"},
+		{"no expiry", signToken(t, rsaKey, with(func(c jwt.MapClaims) { delete(c, "exp") })), nil, "exp claim is required"},
+		{"not yet valid", signToken(t, rsaKey, with(func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() })), nil, "not valid yet"},
+		{"wrong issuer", signToken(t, rsaKey, with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })), nil, "invalid issuer"},
+		{"wrong audience", signToken(t, rsaKey, with(func(c jwt.MapClaims) { c["aud"] = "other" })), nil, "invalid audience"},
+		{"no subject", signToken(t, rsaKey, with(func(c jwt.MapClaims) { delete(c, "sub") })), nil, "no subject"},
+		{"unknown key", signToken(t, signingKey{"ec-2", otherKey.method, otherKey.key}, validClaims()), nil, "unknown signing key"},
+		{"wrong key", signToken(t, signingKey{ecKey.kid, otherKey.method, otherKey.key}, validClaims()), nil, "signature is invalid"},
+		{"HMAC", hmacToken(), nil, "signing method HS256 is invalid"},
+		{"none", noneToken(), nil, "signing method none is invalid"},
+		{"malformed", "not.a.token", nil, "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), tt.token)
+			if tt.err != "" {
+				if !errors.Is(err, ErrInvalidToken) || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error incorrect. got %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if principal.Subject != "alice@example.com" || !reflect.DeepEqual(principal.Scopes, tt.scopes) {
+				t.Errorf("principal incorrect. got %+v, want scopes %v", principal, tt.scopes)
+			}
+		})
+	}
+
+	t.Run("tampered payload", func(t *testing.T) {
+		parts := strings.Split(signToken(t, rsaKey, validClaims()), ".")
+		claims := validClaims()
+		claims["roles"] = []string{"admin"}
+		payload, _ := json.Marshal(claims)
+		parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+		if _, err := verifier.Verify(context.Background(), strings.Join(parts, ".")); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken. got %v", err)
+		}
+	})
+
+	t.Run("public key as HMAC secret", func(t *testing.T) {
+		data := jwksJSON(t, rsaKey)
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+		token.Header["kid"] = rsaKey.kid
+		unsigned, _ := token.SigningString()
+		mac := hmac.New(sha256.New, data)
+		mac.Write([]byte(unsigned))
+		forged := unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+		if _, err := verifier.Verify(context.Background(), forged); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("expected ErrInvalidToken. got %v", err)
+		}
+	})
+}
+
+func TestTokenVerifierRoles(t *testing.T) {
+	rsaKey, _ := newSigningKeys(t)
+	jwksFile := writeJWKSFile(t, rsaKey)
+
+	var tests = []struct {
+		name    string
+		claim   string
+		mapping map[string]string
+		value   interface{}
+		roles   []string
+		scopes  []string
+	}{
+		{"list", "roles", nil, []string{"viewer", "editor"}, []string{"viewer", "editor"}, []string{ScopeProductsRead, ScopeProductsWrite}},
+		{"space-separated", "roles", nil, "viewer admin", []string{"viewer", "admin"}, []string{ScopeProductsRead, ScopeProductsAdmin}},
+		{"duplicates", "roles", nil, []string{"viewer", "viewer"}, []string{"viewer"}, []string{ScopeProductsRead}},
+		{"unknown role", "roles", nil, []string{"guest"}, []string{"guest"}, nil},
+		{"missing claim", "groups", nil, nil, nil, nil},
+		{"nested claim", "realm_access.roles", nil, map[string]interface{}{"roles": []string{"admin"}}, []string{"admin"}, []string{ScopeProductsAdmin}},
+		{"mapping", "groups", map[string]string{"catalog-team": "editor", "sso-admins": "admin"}, []string{"catalog-team", "everyone"}, []string{"editor"}, []string{ScopeProductsWrite}},
+		{"mapping to the same role", "groups", map[string]string{"a": "viewer", "b": "viewer"}, []string{"a", "b"}, []string{"viewer"}, []string{ScopeProductsRead}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testAuthConfig(jwksFile)
+			cfg.JWT.RolesClaim = tt.claim
+			cfg.JWT.RoleMapping = tt.mapping
+			verifier := newTestVerifier(t, cfg)
+
+			claims := validClaims()
+			delete(claims, "roles")
+			if tt.value != nil {
+				name, _, nested := strings.Cut(tt.claim, ".")
+				if nested {
+					claims[name] = tt.value
+				} else {
+					claims[tt.claim] = tt.value
+				}
+			}
+
+			principal, err := verifier.Verify(context.Background(), signToken(t, rsaKey, claims))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(principal.Roles, tt.roles) || !reflect.DeepEqual(principal.Scopes, tt.scopes) {
+				t.Errorf("principal incorrect. got roles %v scopes %v, want %v %v", principal.Roles, principal.Scopes, tt.roles, tt.scopes)
+			}
+		})
+	}
+}
+
+func TestRemoteJWKS(t *testing.T) {
+	rsaKey, ecKey := newSigningKeys(t)
+	published := jwksJSON(t, rsaKey)
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Write(published)
+	}))
+	defer server.Close()
+
+	jwks := NewRemoteJWKS(server.URL, time.Hour)
+	ctx := context.Background()
+
+	if _, err := jwks.Key(ctx, rsaKey.kid); err != nil || fetches.Load() != 1 {
+		t.Fatalf("first lookup should fetch the keys. got %v after %d fetches", err, fetches.Load())
+	}
+	if _, err := jwks.Key(ctx, rsaKey.kid); err != nil || fetches.Load() != 1 {
+		t.Errorf("known keys should be served from memory. got %v after %d fetches", err, fetches.Load())
+	}
+
+	// A key published after the last fetch is picked up once the refetch interval has passed.
+	published = jwksJSON(t, rsaKey, ecKey)
+	if _, err := jwks.Key(ctx, ecKey.kid); !errors.Is(err, ErrInvalidToken) || fetches.Load() != 1 {
+		t.Errorf("unknown keys should not refetch within the interval. got %v after %d fetches", err, fetches.Load())
+	}
+	jwks.triedAt = jwks.triedAt.Add(-jwksRefetchInterval)
+	if _, err := jwks.Key(ctx, ecKey.kid); err != nil || fetches.Load() != 2 {
+		t.Errorf("unknown keys should refetch after the interval. got %v after %d fetches", err, fetches.Load())
+	}
+
+	// Keys already fetched are kept when a refresh fails, and the refresh isn't tried again
+	// within the refetch interval.
+	failing.Store(true)
+	jwks.fetchedAt = jwks.fetchedAt.Add(-time.Hour)
+	jwks.triedAt = jwks.triedAt.Add(-jwksRefetchInterval)
+	if _, err := jwks.Key(ctx, rsaKey.kid); err != nil || fetches.Load() != 3 {
+		t.Errorf("stale keys should be used when a refresh fails. got %v after %d fetches", err, fetches.Load())
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := jwks.Key(ctx, rsaKey.kid); err != nil || fetches.Load() != 3 {
+			t.Errorf("failed refreshes should not be retried within the interval. got %v after %d fetches", err, fetches.Load())
+		}
+	}
+	jwks.triedAt = jwks.triedAt.Add(-jwksRefetchInterval)
+	if _, err := jwks.Key(ctx, rsaKey.kid); err != nil || fetches.Load() != 4 {
+		t.Errorf("failed refreshes should be retried after the interval. got %v after %d fetches", err, fetches.Load())
+	}
+
+	unavailable := NewRemoteJWKS(server.URL, time.Hour)
+	for i := 0; i < 2; i++ {
+		if _, err := unavailable.Key(ctx, rsaKey.kid); !errors.Is(err, ErrJWKSUnavailable) || fetches.Load() != 5 {
+			t.Errorf("expected ErrJWKSUnavailable without keys after one fetch. got %v after %d fetches", err, fetches.Load())
+		}
+	}
+}
+
+func TestRemoteJWKSConcurrentFetch(t *testing.T) {
+	rsaKey, _ := newSigningKeys(t)
+	published := jwksJSON(t, rsaKey)
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		w.Write(published)
+	}))
+	defer server.Close()
+
+	jwks := NewRemoteJWKS(server.URL, time.Hour)
+
+	// A caller that gives up returns at once without cancelling the fetch for the others.
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := jwks.Key(cancelled, rsaKey.kid); !errors.Is(err, ErrJWKSUnavailable) {
+		t.Errorf("expected ErrJWKSUnavailable for a cancelled caller. got %v", err)
+	}
+
+	const callers = 10
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			_, err := jwks.Key(context.Background(), rsaKey.kid)
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	for i := 0; i < callers; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("concurrent lookups should share one fetch. got %d fetches", fetches.Load())
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	var tests = []struct {
+		name string
+		data string
+		kids []string
+		err  bool
+	}{
+		{"RSA", `{"keys":[{"kty":"RSA","kid":"a","n":"AQAB","e":"AQAB"}]}`, []string{"a"}, false},
+		{"Ed25519", `{"keys":[{"kty":"OKP","kid":"a","crv":"Ed25519","x":"` + strings.Repeat("A", 43) + `"}]}`, []string{"a"}, false},
+		{"skips encryption keys", `{"keys":[{"kty":"RSA","kid":"a","use":"enc","n":"AQAB","e":"AQAB"},{"kty":"RSA","kid":"b","n":"AQAB","e":"AQAB"}]}`, []string{"b"}, false},
+		{"skips unknown types", `{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"},{"kty":"RSA","kid":"b","n":"AQAB","e":"AQAB"}]}`, []string{"b"}, false},
+		{"point not on curve", `{"keys":[{"kty":"EC","kid":"a","crv":"P-256","x":"AQ","y":"AQ"}]}`, nil, true},
+		{"bad exponent", `{"keys":[{"kty":"RSA","kid":"a","n":"AQAB","e":"AQ"}]}`, nil, true},
+		{"bad encoding", `{"keys":[{"kty":"RSA","kid":"a","n":"!!","e":"AQAB"}]}`, nil, true},
+		{"no usable keys", `{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"}]}`, nil, true},
+		{"not JSON", `keys`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parseJWKS([]byte(tt.data))
+			if (err != nil) != tt.err {
+				t.Fatalf("error incorrect. got %v, want error %v", err, tt.err)
+			}
+			if len(keys) != len(tt.kids) {
+				t.Fatalf("keys incorrect. got %v, want %v", keys, tt.kids)
+			}
+			for _, kid := range tt.kids {
+				if _, ok := keys[kid]; !ok {
+					t.Errorf("missing key %q", kid)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticatorTokens(t *testing.T) {
+	rsaKey, _ := newSigningKeys(t)
+	authenticator := NewAuthenticator(AuthConfig{Enabled: true}, nil, newTestVerifier(t, testAuthConfig(writeJWKSFile(t, rsaKey))))
+	handler := authenticator.Middleware(requireScope(ScopeProductsWrite, func(w http.ResponseWriter, r *http.Request) {
+		actor, _ := actorFromContext(r.Context())
+		w.Write([]byte(actor))
+	}))
+
+	var tests = []struct {
+		name      string
+		claims    jwt.MapClaims
+		status    int
+		challenge string
+	}{
+		{"editor", validClaims(), http.StatusOK, ""},
+		{"viewer", jwt.MapClaims{"iss": testIssuer, "aud": testAudience, "sub": "bob", "exp": time.Now().Add(time.Hour).Unix(), "roles": "viewer"}, http.StatusForbidden, ""},
+		{"expired", jwt.MapClaims{"iss": testIssuer, "aud": testAudience, "sub": "bob", "exp": time.Now().Add(-time.Hour).Unix(), "roles": "admin"}, http.StatusUnauthorized, `error="invalid_token"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/products", nil)
+			request.Header.Set("Authorization", "Bearer "+signToken(t, rsaKey, tt.claims))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.status {
+				t.Fatalf("status incorrect. got %d, want %d: %s", recorder.Code, tt.status, recorder.Body.String())
+			}
+			if tt.status == http.StatusOK && recorder.Body.String() != "alice@example.com" {
+				t.Errorf("actor incorrect. got %q", recorder.Body.String())
+			}
+			if challenge := recorder.Header().Get("WWW-Authenticate"); !strings.Contains(challenge, tt.challenge) {
+				t.Errorf("challenge incorrect. got %q, want it to contain %q", challenge, tt.challenge)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestLogging(t *testing.T) {
+	var tests = []struct {
+		name       string
+		requestID  string
+		propagated bool
+	}{
+		{"propagates the client id", "abc-123", true},
+		{"generates a missing id", "", false},
+		{"replaces an id with spaces", "not valid", false},
+		{"replaces an overlong id", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.InfoLevel)
+			defer zap.ReplaceGlobals(zap.New(core))()
+
+			var contextID string
+			handler := RequestLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextID = RequestIDFromContext(r.Context())
+				LoggerFromContext(r.Context()).Info("Handling")
+				w.WriteHeader(http.StatusTeapot)
+				w.Write([]byte("short and stout"))
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/products?page=1", nil)
+			if tt.requestID != "" {
+				request.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			requestID := recorder.Header().Get(RequestIDHeader)
+			if tt.propagated && requestID != tt.requestID {
+				t.Errorf("request id incorrect. got %q, want %q", requestID, tt.requestID)
+			}
+			if !tt.propagated && (len(requestID) != 32 || requestID == tt.requestID) {
+				t.Errorf("expected a generated request id. got %q", requestID)
+			}
+			if contextID != requestID {
+				t.Errorf("context request id incorrect. got %q, want %q", contextID, requestID)
+			}
+
+			entries := logs.All()
+			if len(entries) != 2 {
+				t.Fatalf("log entry count incorrect. got %d, want 2", len(entries))
+			}
+			for _, entry := range entries {
+				if entry.ContextMap()["request_id"] != requestID {
+					t.Errorf("entry %q missing request id. got %v", entry.Message, entry.ContextMap())
+				}
+			}
+
+			fields := entries[1].ContextMap()
+			if fields["method"] != http.MethodGet || fields["path"] != "/api/v1/products" {
+				t.Errorf("method/path incorrect. got %v", fields)
+			}
+			if fields["status"] != int64(http.StatusTeapot) || fields["bytes"] != int64(len("short and stout")) {
+				t.Errorf("status/bytes incorrect. got %v", fields)
+			}
+			if _, ok := fields["latency"]; !ok {
+				t.Errorf("latency missing. got %v", fields)
+			}
+		})
+	}
+}
+
+func TestLoggerFromContextFallsBackToGlobal(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	LoggerFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()).Info("outside a request")
+	if logs.Len() != 1 {
+		t.Errorf("expected the global logger to be used")
+	}
+}
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
@@ -14,41 +19,112 @@ import (
 
 func main() {
 
-	logger, err := zap.NewDevelopment()
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	createAPIKey := flag.String("create-api-key", "", "create an API key with this name, print it and exit")
+	apiKeyScopes := flag.String("api-key-scopes", ScopeProductsAdmin, "comma-separated scopes of the key made by -create-api-key")
+	flag.Parse()
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	logger, err := NewLogger(cfg.Log)
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
-	defer logger.Sync()
 	zap.ReplaceGlobals(logger)
 
-	db := InitDatabase()
-	service := NewProductService(db)
-	validator := validator.New()
-	handler := NewProductHandler(service, validator)
-	router := InitRouter(handler)
+	shutdownTracing, err := InitTracing(cfg.Tracing)
+	if err != nil {
+		zap.S().Fatalf("Failed to initialize tracing: %v", err)
+	}
 
-	zap.L().Info("Server is running on port 8080")
-	http.ListenAndServe(":8080", router)
+	db := InitDatabase(cfg.Database)
+	if err := InstrumentTracing(db); err != nil {
+		zap.S().Fatalf("Failed to instrument database tracing: %v", err)
+	}
+	if err := RecordActors(db); err != nil {
+		zap.S().Fatalf("Failed to record actors: %v", err)
+	}
+	validator := NewValidator()
+	apiKeyService := NewAPIKeyService(db)
+	if *createAPIKey != "" {
+		IssueAPIKey(apiKeyService, validator, *createAPIKey, *apiKeyScopes)
+		CloseDatabase(db)
+		logger.Sync()
+		return
+	}
 
-}
+	blobs := InitBlobStore(cfg.Media)
+	service := NewProductService(db, blobs, cfg.Media)
+	handler := NewProductHandler(service, validator, cfg.Pagination)
+	supplierHandler := NewSupplierHandler(NewSupplierService(db), validator)
+	apiKeyHandler := NewAPIKeyHandler(apiKeyService, validator)
+	if !cfg.Auth.Enabled {
+		zap.L().Warn("Authentication is disabled; the API is open to anyone who can reach it")
+	}
+	tokens, err := NewTokenVerifier(cfg.Auth)
+	if err != nil {
+		zap.S().Fatalf("Failed to initialize token verification: %v", err)
+	}
+	health := NewHealthHandler(db, cfg.Database.PingTimeout)
+	metrics := NewMetrics()
+	if err := metrics.InstrumentDatabase(db); err != nil {
+		zap.S().Fatalf("Failed to instrument database: %v", err)
+	}
+	router := InitRouter(handler, supplierHandler, apiKeyHandler, NewAuthenticator(cfg.Auth, apiKeyService, tokens), health, metrics, cfg.Server)
 
-func InitDatabase() *gorm.DB {
+	listener, err := net.Listen("tcp", cfg.Server.Addr)
+	if err != nil {
+		zap.S().Fatalf("Failed to listen on %s: %v", cfg.Server.Addr, err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	zap.L().Info("Server is running", zap.String("addr", listener.Addr().String()))
+	serverErr := RunServer(ctx, NewServer(cfg.Server, router), listener, cfg.Server, func() {
+		// Restore the default signal handling, so that a second signal ends a stuck drain.
+		stop()
+		health.MarkShuttingDown()
+	})
+	if serverErr != nil {
+		zap.L().Error("Server stopped with an error", zap.Error(serverErr))
+	}
+
+	CloseDatabase(db)
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	if err := shutdownTracing(flushCtx); err != nil {
+		zap.L().Error("Failed to flush traces", zap.Error(err))
+	}
+	cancel()
+
+	logger.Sync()
 
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
+	if serverErr != nil {
+		os.Exit(1)
+	}
+}
 
-	dsn := fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%s sslmode=disable",
-		dbUser, dbPassword, dbName, dbHost, dbPort)
+func InitDatabase(cfg DatabaseConfig) *gorm.DB {
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(cfg.ConnectionString()), &gorm.Config{})
 	if err != nil {
 		zap.S().Fatalf("Failed to connect to database: %v", err)
 	}
 
-	if err := db.AutoMigrate(&Product{}); err != nil {
+	sqlDB, err := db.DB()
+	if err != nil {
+		zap.S().Fatalf("Failed to access database pool: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := db.AutoMigrate(schemaModels()...); err != nil {
 		zap.S().Fatalf("Failed to migrate database schema: %v", err)
 	}
 
@@ -57,12 +133,67 @@ func InitDatabase() *gorm.DB {
 		zap.S().Fatalf("Failed to create sku index: %v", err)
 	}
 
+	err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_barcode_not_deleted ON products (barcode) WHERE deleted_at IS NULL AND barcode <> ''").Error
+	if err != nil {
+		zap.S().Fatalf("Failed to create barcode index: %v", err)
+	}
+
+	err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_product_supplier_preferred ON product_suppliers (product_id) WHERE preferred").Error
+	if err != nil {
+		zap.S().Fatalf("Failed to create preferred supplier index: %v", err)
+	}
+
+	err = db.Exec("CREATE INDEX IF NOT EXISTS idx_products_attributes ON products USING GIN (attributes)").Error
+	if err != nil {
+		zap.S().Fatalf("Failed to create attributes index: %v", err)
+	}
+
+	err = db.Exec("CREATE INDEX IF NOT EXISTS idx_products_tags ON products USING GIN (tags)").Error
+	if err != nil {
+		zap.S().Fatalf("Failed to create tags index: %v", err)
+	}
+
 	zap.L().Info("Database connection initialized successfully")
 	return db
 }
 
+// schemaModels lists the models migrated at startup.
+func schemaModels() []interface{} {
+	return []interface{}{&Product{}, &ProductMedia{}, &BundleComponent{}, &Supplier{}, &ProductSupplier{}, &AttributeDefinition{}, &APIKey{}}
+}
+
+// IssueAPIKey creates an API key from the command line and prints it, so the first admin key
+// can be made before any exist to call the API with.
+func IssueAPIKey(service *APIKeyService, validator *validator.Validate, name, scopes string) {
+	request := APIKeyCreateRequest{Name: name, Scopes: strings.Split(scopes, ",")}
+	if err := validator.Struct(request); err != nil {
+		zap.S().Fatalf("Invalid API key request: %v", err)
+	}
+
+	apiKey, err := service.CreateAPIKey(context.Background(), request)
+	if err != nil {
+		zap.S().Fatalf("Failed to create API key: %v", err)
+	}
+
+	zap.L().Info("API key created successfully", zap.Uint("API key ID", apiKey.ID), zap.Strings("scopes", apiKey.Scopes))
+	fmt.Println(apiKey.Key)
+}
+
+// InitBlobStore opens the local media directory.
+func InitBlobStore(cfg MediaConfig) BlobStore {
+	dir := cfg.Dir
+
+	blobs, err := NewLocalBlobStore(dir)
+	if err != nil {
+		zap.S().Fatalf("Failed to initialize media storage: %v", err)
+	}
+
+	zap.L().Info("Media storage initialized successfully", zap.String("dir", dir))
+	return blobs
+}
+
 func CleanDatabase(db *gorm.DB) {
-	if err := db.Migrator().DropTable(&Product{}); err != nil {
-		zap.S().Fatalf("Failed to drop products table: %v", err)
+	if err := db.Migrator().DropTable(&ProductSupplier{}, &Supplier{}, &BundleComponent{}, &ProductMedia{}, &Product{}, &AttributeDefinition{}, &APIKey{}); err != nil {
+		zap.S().Fatalf("Failed to drop tables: %v", err)
 	}
 }
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestProductMedia(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	product := getSampleProductRequests()[0]
+	productID := int(e.POST("/api/v1/products").WithJSON(product).
+		Expect().
+		Status(http.StatusCreated).
+		JSON().Object().Value("id").Number().Raw())
+	mediaPath := "/api/v1/products/" + strconv.Itoa(productID) + "/media"
+
+	testCases := []struct {
+		name           string
+		filename       string
+		content        []byte
+		expectedStatus int
+	}{
+		{"Valid png", "first.png", samplePNG(t, 800, 400), http.StatusCreated},
+		{"Second valid png", "second.png", samplePNG(t, 10, 10), http.StatusCreated},
+		{"Third valid png", "third.png", samplePNG(t, 20, 10), http.StatusCreated},
+		{"Not an image", "notes.txt", []byte("just some text"), http.StatusUnsupportedMediaType},
+		{"Truncated image", "broken.png", samplePNG(t, 10, 10)[:40], http.StatusBadRequest},
+		{"Oversized dimensions", "bomb.png", pngWithDimensions(t, 100000, 100000), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.POST(mediaPath).
+				WithMultipart().
+				WithFileBytes("file", tc.filename, tc.content).
+				Expect().
+				Status(tc.expectedStatus)
+		})
+	}
+
+	// uploads to missing products fail
+	e.POST("/api/v1/products/1000000/media").
+		WithMultipart().
+		WithFileBytes("file", "first.png", samplePNG(t, 10, 10)).
+		Expect().
+		Status(http.StatusNotFound)
+
+	media := e.GET(mediaPath).Expect().Status(http.StatusOK).JSON().Array()
+	media.Length().IsEqual(3)
+	first := media.Value(0).Object()
+	first.Value("is_primary").Boolean().IsTrue()
+	first.Value("width").Number().IsEqual(800)
+	first.Value("content_type").String().IsEqual("image/png")
+	firstID := int(first.Value("id").Number().Raw())
+	thirdID := int(media.Value(2).Object().Value("id").Number().Raw())
+
+	// the thumbnail is scaled down, the original is served unchanged
+	thumbnail := e.GET(mediaPath + "/" + strconv.Itoa(firstID) + "/thumbnail").Expect().Status(http.StatusOK).Body().Raw()
+	config, err := png.DecodeConfig(bytes.NewReader([]byte(thumbnail)))
+	if err != nil {
+		t.Fatalf("thumbnail is not a png: %v", err)
+	}
+	if config.Width != ThumbnailSize || config.Height != ThumbnailSize/2 {
+		t.Errorf("thumbnail size incorrect. got %dx%d", config.Width, config.Height)
+	}
+	e.GET(mediaPath + "/" + strconv.Itoa(firstID) + "/content").Expect().Status(http.StatusOK).
+		Header("Content-Type").IsEqual("image/png")
+
+	// move the third image to the front and make it primary
+	e.PATCH(mediaPath + "/" + strconv.Itoa(thirdID)).
+		WithJSON(map[string]interface{}{"position": 0, "is_primary": true}).
+		Expect().
+		Status(http.StatusOK)
+	e.PATCH(mediaPath + "/" + strconv.Itoa(thirdID)).
+		WithJSON(map[string]interface{}{"position": 3}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	media = e.GET(mediaPath).Expect().Status(http.StatusOK).JSON().Array()
+	media.Value(0).Object().Value("id").Number().IsEqual(thirdID)
+	media.Value(0).Object().Value("is_primary").Boolean().IsTrue()
+	media.Value(1).Object().Value("id").Number().IsEqual(firstID)
+	media.Value(1).Object().Value("is_primary").Boolean().IsFalse()
+
+	// deleting the primary image promotes the next one
+	e.DELETE(mediaPath + "/" + strconv.Itoa(thirdID)).Expect().Status(http.StatusNoContent)
+	media = e.GET(mediaPath).Expect().Status(http.StatusOK).JSON().Array()
+	media.Length().IsEqual(2)
+	media.Value(0).Object().Value("id").Number().IsEqual(firstID)
+	media.Value(0).Object().Value("is_primary").Boolean().IsTrue()
+	media.Value(1).Object().Value("position").Number().IsEqual(1)
+
+	// purging the product removes its media and files
+	e.DELETE("/api/v1/products/"+strconv.Itoa(productID)).WithQuery("purge", "true").
+		Expect().
+		Status(http.StatusNoContent)
+	e.GET(mediaPath).Expect().Status(http.StatusNotFound)
+
+	var remaining int64
+	db.Model(&ProductMedia{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("media rows left after purge: %d", remaining)
+	}
+
+	// the SKU can be reused after purging
+	e.POST("/api/v1/products").WithJSON(product).Expect().Status(http.StatusCreated)
+}
+
+func TestConcurrentMediaUploads(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	productID := int(e.POST("/api/v1/products").WithJSON(getSampleProductRequests()[0]).
+		Expect().Status(http.StatusCreated).JSON().Object().Value("id").Number().Raw())
+	mediaPath := "/api/v1/products/" + strconv.Itoa(productID) + "/media"
+
+	const uploads = 8
+	var wg sync.WaitGroup
+	for i := 0; i < uploads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.POST(mediaPath).WithMultipart().WithFileBytes("file", "image.png", samplePNG(t, 10, 10)).
+				Expect().Status(http.StatusCreated)
+		}()
+	}
+	wg.Wait()
+
+	// each upload is appended at its own position and only one becomes primary
+	media := e.GET(mediaPath).Expect().Status(http.StatusOK).JSON().Array()
+	media.Length().IsEqual(uploads)
+	primaries := 0
+	for i := 0; i < uploads; i++ {
+		item := media.Value(i).Object()
+		item.Value("position").Number().IsEqual(i)
+		if item.Value("is_primary").Boolean().Raw() {
+			primaries++
+		}
+	}
+	if primaries != 1 {
+		t.Errorf("expected one primary image. got %d", primaries)
+	}
+}
+
+func samplePNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
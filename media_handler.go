@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// multipartOverhead is the allowance for multipart headers and boundaries on top of MaxMediaSize.
+const multipartOverhead = 1 << 20
+
+func (h *ProductHandler) UploadProductMedia(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Upload product media", zap.String("path", r.URL.Path))
+
+	productID, ok := parsePathID(w, r, "id", "invalid product ID")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxMediaSize+multipartOverhead)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			logger.Info("Failed to upload product media because request body was too large", zap.Error(err))
+			http.Error(w, ErrMediaTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		logger.Info("Failed to upload product media because the file part could not be read", zap.Error(err))
+		http.Error(w, "request must be multipart/form-data with a 'file' part", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxMediaSize+1))
+	if err != nil {
+		logger.Error("Failed to upload product media because file could not be read", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	media, err := h.productService.AddProductMedia(r.Context(), productID, header.Filename, data)
+	if err != nil {
+		h.handleMediaError(w, r, "Failed to upload product media", err)
+		return
+	}
+
+	logger.Info("Product media uploaded successfully", zap.Int("product ID", productID), zap.Uint("media ID", media.ID))
+	httpCreated(w, media)
+}
+
+func (h *ProductHandler) GetProductMediaList(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get product media", zap.String("path", r.URL.Path))
+
+	productID, ok := parsePathID(w, r, "id", "invalid product ID")
+	if !ok {
+		return
+	}
+
+	media, err := h.productService.GetProductMediaList(r.Context(), productID)
+	if err != nil {
+		h.handleMediaError(w, r, "Failed to get product media", err)
+		return
+	}
+
+	httpOK(w, media)
+}
+
+func (h *ProductHandler) GetProductMedia(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get product media item", zap.String("path", r.URL.Path))
+
+	productID, ok := parsePathID(w, r, "id", "invalid product ID")
+	if !ok {
+		return
+	}
+	mediaID, ok := parsePathID(w, r, "mediaId", "invalid media ID")
+	if !ok {
+		return
+	}
+
+	media, err := h.productService.GetProductMedia(r.Context(), productID, mediaID)
+	if err != nil {
+		h.handleMediaError(w, r, "Failed to get product media item", err)
+		return
+	}
+
+	httpOK(w, media)
+}
+
+func (h *ProductHandler) GetProductMediaContent(w http.ResponseWriter, r *http.Request) {
+	h.serveProductMedia(w, r, false)
+}
+
+func (h *ProductHandler) GetProductMediaThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveProductMedia(w, r, true)
+}
+
+func (h *ProductHandler) serveProductMedia(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Serve product media", zap.String("path", r.URL.Path))
+
+	productID, ok := parsePathID(w, r, "id", "invalid product ID")
+	if !ok {
+		return
+	}
+	mediaID, ok := parsePathID(w, r, "mediaId", "invalid media ID")
+	if !ok {
+		return
+	}
+
+	media, reader, err := h.productService.OpenProductMedia(r.Context(), productID, mediaID, thumbnail)
+	if err != nil {
+		h.handleMediaError(w, r, "Failed to serve product media", err)
+		return
+	}
+	defer reader.Close()
+
+	contentType := media.ContentType
+	if thumbnail {
+		contentType = "image/png"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, reader); err != nil {
+		logger.Error("Failed to write product media", zap.Uint("media ID", media.ID), zap.Error(err))
+	}
+}
+
+func (h *ProductHandler) UpdateProductMedia(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Update product media", zap.String("path", r.URL.Path))
+
+	productID, ok := parsePathID(w, r, "id", "invalid product ID")
+	if !ok {
+		return
+	}
+	mediaID, ok := parsePathID(w, r, "mediaId", "invalid media ID")
+	if !ok {
+		return
+	}
+
+	var request ProductMediaUpdateRequest
+	err := decodeJSON(r.Body, &request)
+	if err != nil {
+		writeBodyError(w, r, "update product media", err)
+		return
+	}
+
+	err = h.validator.Struct(request)
+	if err != nil {
+		if validationErrors, ok := err.(validator.ValidationErrors); ok {
+			logger.Info("Failed to update product media because request failed validation", zap.Any("validationErrors", validationErrors))
+			httpBadRequest(w, formatValidationErrors(validationErrors))
+			return
+		}
+		logger.Error("Unexpected error occurred during ProductMediaUpdateRequest validation", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	media, err := h.productService.UpdateProductMedia(r.Context(), productID, mediaID, request)
+	if err != nil {
+		h.handleMediaError(w, r, "Failed to update product media", err)
+		return
+	}
+
+	logger.Info("Product media updated successfully", zap.Uint("media ID", media.ID))
+	httpOK(w, media)
+}
+
+func (h *ProductHandler) DeleteProductMedia(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Delete product media", zap.String("path", r.URL.Path))
+
+	productID, ok := parsePathID(w, r, "id", "invalid product ID")
+	if !ok {
+		return
+	}
+	mediaID, ok := parsePathID(w, r, "mediaId", "invalid media ID")
+	if !ok {
+		return
+	}
+
+	err := h.productService.DeleteProductMedia(r.Context(), productID, mediaID)
+	if err != nil {
+		h.handleMediaError(w, r, "Failed to delete product media", err)
+		return
+	}
+
+	logger.Info("Product media deleted successfully", zap.Int("media ID", mediaID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ProductHandler) handleMediaError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	logger := LoggerFromContext(r.Context())
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrMediaNotFound), errors.Is(err, ErrBlobNotFound):
+		logger.Info(msg, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrMediaTooLarge):
+		logger.Info(msg, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrUnsupportedMediaType):
+		logger.Info(msg, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, ErrInvalidImage), errors.Is(err, ErrMediaPositionOutOfRange):
+		logger.Info(msg, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error(msg, zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+	}
+}
+
+// parsePathID reads the integer path variable name, writing a 400 response if it is invalid.
+func parsePathID(w http.ResponseWriter, r *http.Request, name, msg string) (int, bool) {
+	logger := LoggerFromContext(r.Context())
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		logger.Info("Request path contained an invalid ID", zap.String("path", r.URL.Path), zap.String("param", name))
+		http.Error(w, msg, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
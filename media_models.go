@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const MaxMediaSize = 10 << 20
+
+// AllowedMediaTypes maps the accepted upload content types to their file extensions.
+var AllowedMediaTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// checkImageDimensions reads only the header of an image and rejects one whose declared size
+// exceeds the limits in cfg, so that a small file claiming huge dimensions is refused before
+// decoding allocates memory for every pixel.
+func checkImageDimensions(data []byte, cfg MediaConfig) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width > cfg.MaxWidth || config.Height > cfg.MaxHeight || int64(config.Width)*int64(config.Height) > int64(cfg.MaxPixels) {
+		return fmt.Errorf("%w: %dx%d pixels exceeds the limit of %dx%d and %d pixels in total",
+			ErrMediaTooLarge, config.Width, config.Height, cfg.MaxWidth, cfg.MaxHeight, cfg.MaxPixels)
+	}
+	return nil
+}
+
+type ProductMedia struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ProductID    uint      `gorm:"not null;index" json:"product_id"`
+	BlobKey      string    `gorm:"type:text;not null" json:"-"`
+	ThumbnailKey string    `gorm:"type:text;not null" json:"-"`
+	Filename     string    `gorm:"type:text" json:"filename"`
+	ContentType  string    `gorm:"type:varchar(64);not null" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	Width        int       `gorm:"not null" json:"width"`
+	Height       int       `gorm:"not null" json:"height"`
+	Position     int       `gorm:"not null" json:"position"`
+	IsPrimary    bool      `gorm:"not null;default:false" json:"is_primary"`
+	URL          string    `gorm:"-" json:"url"`
+	ThumbnailURL string    `gorm:"-" json:"thumbnail_url"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (m *ProductMedia) AfterFind(_ *gorm.DB) error {
+	m.setURLs()
+	return nil
+}
+
+func (m *ProductMedia) AfterCreate(_ *gorm.DB) error {
+	m.setURLs()
+	return nil
+}
+
+func (m *ProductMedia) setURLs() {
+	base := fmt.Sprintf("/api/v1/products/%d/media/%d", m.ProductID, m.ID)
+	m.URL = base + "/content"
+	m.ThumbnailURL = base + "/thumbnail"
+}
+
+type ProductMediaUpdateRequest struct {
+	Position  *int  `json:"position,omitempty" validate:"omitempty,min=0"`
+	IsPrimary *bool `json:"is_primary,omitempty"`
+}
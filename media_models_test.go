@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// pngWithDimensions returns a 1x1 PNG whose header claims to be width by height pixels, as a
+// decompression bomb would.
+func pngWithDimensions(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	data := buf.Bytes()
+	// The IHDR chunk follows the 8-byte signature: length, type, width, height, ..., CRC.
+	binary.BigEndian.PutUint32(data[16:20], width)
+	binary.BigEndian.PutUint32(data[20:24], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestCheckImageDimensions(t *testing.T) {
+	cfg := MediaConfig{MaxWidth: 1000, MaxHeight: 800, MaxPixels: 500_000}
+
+	var tests = []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"within limits", pngWithDimensions(t, 1000, 500), nil},
+		{"too wide", pngWithDimensions(t, 1001, 10), ErrMediaTooLarge},
+		{"too tall", pngWithDimensions(t, 10, 801), ErrMediaTooLarge},
+		{"too many pixels", pngWithDimensions(t, 1000, 800), ErrMediaTooLarge},
+		{"decompression bomb", pngWithDimensions(t, 50000, 50000), ErrMediaTooLarge},
+		{"not an image", []byte("just some text"), ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkImageDimensions(tt.data, cfg); !errors.Is(err, tt.err) {
+				t.Errorf("error incorrect. got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AddProductMedia validates an uploaded image, stores it and its thumbnail in the blob store
+// and appends it to the product's media. The first image of a product becomes its primary image.
+func (s *ProductService) AddProductMedia(ctx context.Context, productID int, filename string, data []byte) (*ProductMedia, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.AddProductMedia")
+	defer span.End()
+
+	if _, err := s.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	if len(data) > MaxMediaSize {
+		return nil, ErrMediaTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := AllowedMediaTypes[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, contentType)
+	}
+
+	if err := checkImageDimensions(data, s.media); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	var thumbnail bytes.Buffer
+	if err := png.Encode(&thumbnail, GenerateThumbnail(img, ThumbnailSize)); err != nil {
+		return nil, err
+	}
+
+	name, err := randomBlobName()
+	if err != nil {
+		return nil, err
+	}
+
+	media := ProductMedia{
+		ProductID:    uint(productID),
+		BlobKey:      fmt.Sprintf("products/%d/%s%s", productID, name, ext),
+		ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb.png", productID, name),
+		Filename:     filename,
+		ContentType:  contentType,
+		Size:         int64(len(data)),
+		Width:        img.Bounds().Dx(),
+		Height:       img.Bounds().Dy(),
+	}
+
+	if err := s.blobs.Put(media.BlobKey, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if err := s.blobs.Put(media.ThumbnailKey, &thumbnail); err != nil {
+		s.deleteBlobs(ctx, media)
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&ProductMedia{}).Where("product_id = ?", productID).Count(&count).Error; err != nil {
+			return err
+		}
+
+		media.Position = int(count)
+		media.IsPrimary = count == 0
+
+		return tx.Create(&media).Error
+	})
+	if err != nil {
+		s.deleteBlobs(ctx, media)
+		return nil, err
+	}
+
+	return &media, nil
+}
+
+func (s *ProductService) GetProductMediaList(ctx context.Context, productID int) ([]ProductMedia, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProductMediaList")
+	defer span.End()
+
+	if _, err := s.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	media := []ProductMedia{}
+
+	err := s.db.WithContext(ctx).Where("product_id = ?", productID).Order("position ASC").Find(&media).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+func (s *ProductService) GetProductMedia(ctx context.Context, productID, mediaID int) (*ProductMedia, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProductMedia")
+	defer span.End()
+
+	if _, err := s.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	var media ProductMedia
+
+	err := s.db.WithContext(ctx).Where("id = ? AND product_id = ?", mediaID, productID).First(&media).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, err
+	}
+
+	return &media, nil
+}
+
+// OpenProductMedia returns the media record and a reader for the original image, or for its
+// thumbnail if thumbnail is set. The caller must close the reader.
+func (s *ProductService) OpenProductMedia(ctx context.Context, productID, mediaID int, thumbnail bool) (*ProductMedia, io.ReadCloser, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.OpenProductMedia")
+	defer span.End()
+
+	media, err := s.GetProductMedia(ctx, productID, mediaID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := media.BlobKey
+	if thumbnail {
+		key = media.ThumbnailKey
+	}
+
+	reader, err := s.blobs.Get(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return media, reader, nil
+}
+
+// UpdateProductMedia moves an image to a new position, shifting the others, and/or makes it
+// the product's primary image.
+func (s *ProductService) UpdateProductMedia(ctx context.Context, productID, mediaID int, req ProductMediaUpdateRequest) (*ProductMedia, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.UpdateProductMedia")
+	defer span.End()
+
+	media, err := s.GetProductMedia(ctx, productID, mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+		if err := reloadMedia(tx, media); err != nil {
+			return err
+		}
+
+		if req.Position != nil {
+			var all []ProductMedia
+			if err := tx.Where("product_id = ?", productID).Order("position ASC").Find(&all).Error; err != nil {
+				return err
+			}
+			if *req.Position >= len(all) {
+				return ErrMediaPositionOutOfRange
+			}
+
+			ordered := make([]uint, 0, len(all))
+			for _, m := range all {
+				if m.ID != media.ID {
+					ordered = append(ordered, m.ID)
+				}
+			}
+			ordered = append(ordered[:*req.Position], append([]uint{media.ID}, ordered[*req.Position:]...)...)
+
+			if err := renumberMedia(tx, ordered); err != nil {
+				return err
+			}
+			media.Position = *req.Position
+		}
+
+		if req.IsPrimary != nil && *req.IsPrimary && !media.IsPrimary {
+			if err := setPrimaryMedia(tx, productID, media.ID); err != nil {
+				return err
+			}
+			media.IsPrimary = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+// DeleteProductMedia removes an image, closes the gap in the ordering and, if it was the
+// primary image, promotes the first remaining image.
+func (s *ProductService) DeleteProductMedia(ctx context.Context, productID, mediaID int) error {
+	ctx, span := tracer.Start(ctx, "ProductService.DeleteProductMedia")
+	defer span.End()
+
+	media, err := s.GetProductMedia(ctx, productID, mediaID)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+		if err := reloadMedia(tx, media); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(media).Error; err != nil {
+			return err
+		}
+
+		var remaining []uint
+		if err := tx.Model(&ProductMedia{}).Where("product_id = ?", productID).Order("position ASC").Pluck("id", &remaining).Error; err != nil {
+			return err
+		}
+		if err := renumberMedia(tx, remaining); err != nil {
+			return err
+		}
+
+		if media.IsPrimary && len(remaining) > 0 {
+			return setPrimaryMedia(tx, productID, remaining[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deleteBlobs(ctx, *media)
+	return nil
+}
+
+// reloadMedia reads media again once the product is locked, as another request may have moved
+// or deleted it since it was first read.
+func reloadMedia(tx *gorm.DB, media *ProductMedia) error {
+	err := tx.Where("id = ? AND product_id = ?", media.ID, media.ProductID).First(media).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrMediaNotFound
+	}
+	return err
+}
+
+// PurgeProduct permanently deletes a product, whether or not it has been soft-deleted,
+// together with its media and the stored image files.
+func (s *ProductService) PurgeProduct(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "ProductService.PurgeProduct")
+	defer span.End()
+
+	var media []ProductMedia
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkNotBundleComponent(tx, id, true); err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", id).Find(&media).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("product_id = ?", id).Delete(&ProductMedia{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Delete(&Product{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range media {
+		s.deleteBlobs(ctx, m)
+	}
+	return nil
+}
+
+// deleteBlobs removes the stored files of media. Failures only leave orphaned files behind,
+// so they are logged rather than returned.
+func (s *ProductService) deleteBlobs(ctx context.Context, media ProductMedia) {
+	for _, key := range []string{media.BlobKey, media.ThumbnailKey} {
+		if err := s.blobs.Delete(key); err != nil {
+			LoggerFromContext(ctx).Warn("Failed to delete media blob", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+func renumberMedia(tx *gorm.DB, ids []uint) error {
+	for position, id := range ids {
+		if err := tx.Model(&ProductMedia{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setPrimaryMedia(tx *gorm.DB, productID int, mediaID uint) error {
+	err := tx.Model(&ProductMedia{}).Where("product_id = ? AND id <> ?", productID, mediaID).Update("is_primary", false).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&ProductMedia{}).Where("id = ?", mediaID).Update("is_primary", true).Error
+}
+
+func randomBlobName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// unmatchedRoute labels requests that matched no route, so probing random paths can't create
+// unbounded label values.
+const unmatchedRoute = "unmatched"
+
+// catalogScrapeTimeout bounds the catalog query run on each scrape.
+const catalogScrapeTimeout = 2 * time.Second
+
+// Metrics holds the service's Prometheus collectors in a registry of its own.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	queryDuration   *prometheus.HistogramVec
+	queryErrors     *prometheus.CounterVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests handled, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency, by method, route template and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Database statement latency, by operation and table.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_query_errors_total",
+			Help: "Database statements that failed, by operation and table. Not-found results are not errors.",
+		}, []string{"operation", "table"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.requestDuration, m.queryDuration, m.queryErrors,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// InstrumentDatabase records the duration and errors of every statement run through db and
+// exports the connection pool statistics and catalog gauges.
+func (m *Metrics) InstrumentDatabase(db *gorm.DB) error {
+	if err := db.Use(&queryMetricsPlugin{metrics: m}); err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return registerAll(m.registry, collectors.NewDBStatsCollector(sqlDB, "catalog"), newCatalogCollector(db))
+}
+
+func registerAll(registry *prometheus.Registry, cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the metrics in the Prometheus text format. A failing collector is logged and
+// skipped rather than failing the whole scrape.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorLog:      zap.NewStdLog(zap.L()),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// Middleware counts and times requests, labelled by the template of the matched route.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		labels := prometheus.Labels{
+			"method": r.Method,
+			"route":  routeTemplate(r),
+			"status": strconv.Itoa(recorder.status),
+		}
+		m.requests.With(labels).Inc()
+		m.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+var routeVariablePattern = regexp.MustCompile(`\{([^:}]+):[^}]*\}`)
+
+// routeTemplate returns the matched route's path template with variable patterns removed,
+// e.g. /api/v1/products/{id}.
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return unmatchedRoute
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return routeVariablePattern.ReplaceAllString(template, "{$1}")
+}
+
+const queryStartKey = "metrics:query_start"
+
+// queryMetricsPlugin is a GORM plugin timing every statement.
+type queryMetricsPlugin struct {
+	metrics *Metrics
+}
+
+func (p *queryMetricsPlugin) Name() string {
+	return "metrics"
+}
+
+func (p *queryMetricsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("metrics:before_create", p.before),
+		cb.Create().After("gorm:create").Register("metrics:after_create", p.after("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", p.before),
+		cb.Query().After("gorm:query").Register("metrics:after_query", p.after("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", p.before),
+		cb.Update().After("gorm:update").Register("metrics:after_update", p.after("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", p.after("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", p.before),
+		cb.Row().After("gorm:row").Register("metrics:after_row", p.after("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", p.after("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *queryMetricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (p *queryMetricsPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		p.metrics.queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			p.metrics.queryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
+
+// catalogCollector reports product counts, queried when metrics are scraped.
+type catalogCollector struct {
+	db         *gorm.DB
+	products   *prometheus.Desc
+	outOfStock *prometheus.Desc
+}
+
+func newCatalogCollector(db *gorm.DB) *catalogCollector {
+	return &catalogCollector{
+		db: db,
+		products: prometheus.NewDesc("catalog_products",
+			"Products that are not deleted, by status.", []string{"status"}, nil),
+		outOfStock: prometheus.NewDesc("catalog_products_out_of_stock",
+			"Simple products with no stock, by status. Bundles are excluded as their stock is derived.", []string{"status"}, nil),
+	}
+}
+
+func (c *catalogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.products
+	ch <- c.outOfStock
+}
+
+func (c *catalogCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), catalogScrapeTimeout)
+	defer cancel()
+
+	var rows []struct {
+		Status     string
+		Count      int64
+		OutOfStock int64
+	}
+	err := c.db.WithContext(ctx).Model(&Product{}).
+		Select("status, COUNT(*) AS count, COUNT(*) FILTER (WHERE quantity = 0 AND type = ?) AS out_of_stock", ProductTypeSimple).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.products, err)
+		return
+	}
+
+	for _, status := range productStatuses {
+		var count, outOfStock int64
+		for _, row := range rows {
+			if row.Status == status {
+				count, outOfStock = row.Count, row.OutOfStock
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.products, prometheus.GaugeValue, float64(count), status)
+		ch <- prometheus.MustNewConstMetric(c.outOfStock, prometheus.GaugeValue, float64(outOfStock), status)
+	}
+}
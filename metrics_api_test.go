@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	requests := getSampleProductRequests()
+	requests[1].Quantity = 0
+	for _, request := range requests {
+		e.POST("/api/v1/products").WithJSON(request).Expect().Status(http.StatusCreated)
+	}
+	e.GET("/api/v1/products/1").Expect().Status(http.StatusOK)
+	e.GET("/api/v1/products/1000000").Expect().Status(http.StatusNotFound)
+
+	body := e.GET("/metrics").Expect().Status(http.StatusOK).Body()
+
+	body.Contains(`http_requests_total{method="POST",route="/api/v1/products",status="201"}`)
+	body.Contains(`http_requests_total{method="GET",route="/api/v1/products/{id}",status="200"} 1`)
+	body.Contains(`http_requests_total{method="GET",route="/api/v1/products/{id}",status="404"} 1`)
+	body.NotContains(`/api/v1/products/1000000`)
+	body.Contains(`http_request_duration_seconds_bucket{method="GET",route="/api/v1/products/{id}",status="200",le="+Inf"} 1`)
+
+	body.Contains(`db_query_duration_seconds_count{operation="create",table="products"}`)
+	body.Contains(`db_query_duration_seconds_count{operation="query",table="products"}`)
+	body.Contains(`go_sql_open_connections{db_name="catalog"}`)
+
+	body.Contains(`catalog_products{status="active"} 3`)
+	body.Contains(`catalog_products{status="archived"} 0`)
+	body.Contains(`catalog_products_out_of_stock{status="active"} 1`)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	metrics := NewMetrics()
+	router := mux.NewRouter()
+	router.Use(metrics.Middleware)
+	router.NotFoundHandler = metrics.Middleware(http.NotFoundHandler())
+	router.HandleFunc("/products/{id:[0-9]+}", func(w http.ResponseWriter, _ *http.Request) {}).Methods(http.MethodGet)
+	router.HandleFunc("/products/{id:[0-9]+}/{transition:activate|archive}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}).Methods(http.MethodPost)
+
+	requests := []struct{ method, path string }{
+		{http.MethodGet, "/products/1"},
+		{http.MethodGet, "/products/2"},
+		{http.MethodPost, "/products/3/archive"},
+		{http.MethodGet, "/random/path/1"},
+		{http.MethodGet, "/random/path/2"},
+	}
+	for _, req := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.path, nil))
+	}
+
+	var tests = []struct {
+		method, route, status string
+		count                 float64
+	}{
+		{"GET", "/products/{id}", "200", 2},
+		{"POST", "/products/{id}/{transition}", "409", 1},
+		{"GET", unmatchedRoute, "404", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			got := testutil.ToFloat64(metrics.requests.WithLabelValues(tt.method, tt.route, tt.status))
+			if got != tt.count {
+				t.Errorf("request count incorrect. got %v, want %v", got, tt.count)
+			}
+		})
+	}
+
+	if got := testutil.CollectAndCount(metrics.requests); got != len(tests) {
+		t.Errorf("series count incorrect. got %d, want %d", got, len(tests))
+	}
+	if got := testutil.CollectAndCount(metrics.requestDuration); got != len(tests) {
+		t.Errorf("histogram series count incorrect. got %d, want %d", got, len(tests))
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	MediaTypeMergePatch = "application/merge-patch+json"
+	MediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+// patchDocument is the JSON form of a product that patches are applied to. Unlike
+// ProductCreateRequest it keeps every member, with empty lists and objects written as [] and {},
+// so that operations such as "add /tags/-" or "test /description" find their target.
+type patchDocument struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	SKU         string                   `json:"sku"`
+	Barcode     string                   `json:"barcode"`
+	Price       float64                  `json:"price"`
+	Quantity    int                      `json:"quantity"`
+	Category    string                   `json:"category"`
+	Attributes  map[string]interface{}   `json:"attributes"`
+	Tags        []string                 `json:"tags"`
+	Type        string                   `json:"type"`
+	Pricing     string                   `json:"pricing"`
+	Components  []BundleComponentRequest `json:"components"`
+}
+
+func newPatchDocument(doc ProductCreateRequest) patchDocument {
+	result := patchDocument{
+		Name:        doc.Name,
+		Description: doc.Description,
+		SKU:         doc.SKU,
+		Barcode:     doc.Barcode,
+		Price:       doc.Price,
+		Quantity:    doc.Quantity,
+		Category:    doc.Category,
+		Attributes:  doc.Attributes,
+		Tags:        doc.Tags,
+		Type:        doc.Type,
+		Pricing:     doc.Pricing,
+		Components:  doc.Components,
+	}
+	if result.Attributes == nil {
+		result.Attributes = map[string]interface{}{}
+	}
+	if result.Tags == nil {
+		result.Tags = []string{}
+	}
+	if result.Components == nil {
+		result.Components = []BundleComponentRequest{}
+	}
+	return result
+}
+
+// ApplyProductPatch applies a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) document to
+// the create-request form of a product and returns the patched request. Members set to null
+// in a merge patch are removed and so reset to their defaults; members a product doesn't have
+// are rejected. Lists and objects left empty by the patch are returned as nil, as if they had
+// been omitted. A failed JSON Patch test operation is reported as ErrPatchTestFailed.
+func ApplyProductPatch(mediaType string, doc ProductCreateRequest, patch []byte) (ProductCreateRequest, error) {
+	original, err := json.Marshal(newPatchDocument(doc))
+	if err != nil {
+		return doc, err
+	}
+
+	var patched []byte
+	switch mediaType {
+	case MediaTypeMergePatch:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case MediaTypeJSONPatch:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = operations.Apply(original)
+		}
+	default:
+		return doc, fmt.Errorf("%w: unsupported patch type %s", ErrInvalidPatch, mediaType)
+	}
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return doc, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+		}
+		return doc, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var result ProductCreateRequest
+	if err := decodeJSON(bytes.NewReader(patched), &result); err != nil {
+		return doc, fmt.Errorf("%w: patched product is not valid: %v", ErrInvalidPatch, err)
+	}
+	if len(result.Attributes) == 0 {
+		result.Attributes = nil
+	}
+	if len(result.Tags) == 0 {
+		result.Tags = nil
+	}
+	if len(result.Components) == 0 {
+		result.Components = nil
+	}
+	return result, nil
+}
+
+// productDocument returns the create-request form of a product, which patches are applied
+// to. Status is left out because it is changed through the transition endpoints.
+func productDocument(product *Product) ProductCreateRequest {
+	doc := ProductCreateRequest{
+		Name:        product.Name,
+		Description: product.Description,
+		SKU:         product.SKU,
+		Barcode:     product.Barcode,
+		Price:       product.Price,
+		Quantity:    product.Quantity,
+		Category:    product.Category,
+		Attributes:  product.Attributes,
+		Tags:        product.Tags,
+		Type:        product.Type,
+		Pricing:     product.Pricing,
+	}
+	for _, component := range product.Components {
+		doc.Components = append(doc.Components, BundleComponentRequest{
+			ComponentID: component.ComponentID,
+			Quantity:    component.Quantity,
+		})
+	}
+	return doc
+}
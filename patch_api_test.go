@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestPatchProductDocuments(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	request := getSampleProductRequests()[0]
+	request.Tags = []string{"sale", "eco"}
+	e.POST("/api/v1/products").WithJSON(request).Expect().Status(http.StatusCreated)
+	e.POST("/api/v1/products").WithJSON(getSampleProductRequests()[1]).Expect().Status(http.StatusCreated)
+
+	t.Run("Merge patch clears with null", func(t *testing.T) {
+		product := e.PATCH("/api/v1/products/1").
+			WithHeader("Content-Type", MediaTypeMergePatch).
+			WithBytes([]byte(`{"description": null, "quantity": 7}`)).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+		product.Value("description").String().IsEqual("")
+		product.Value("quantity").Number().IsEqual(7)
+		product.Value("name").String().IsEqual("first product")
+		product.Value("tags").Array().IsEqual([]string{"sale", "eco"})
+	})
+
+	t.Run("Plain JSON null is still ignored", func(t *testing.T) {
+		e.PATCH("/api/v1/products/1").
+			WithHeader("Content-Type", "application/json").
+			WithBytes([]byte(`{"name": null}`)).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("name").String().IsEqual("first product")
+	})
+
+	t.Run("JSON patch with test operations", func(t *testing.T) {
+		e.PATCH("/api/v1/products/1").
+			WithHeader("Content-Type", MediaTypeJSONPatch).
+			WithBytes([]byte(`[{"op": "test", "path": "/quantity", "value": 7}, {"op": "remove", "path": "/tags/0"}, {"op": "add", "path": "/tags/-", "value": "new"}]`)).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("tags").Array().IsEqual([]string{"eco", "new"})
+
+		e.PATCH("/api/v1/products/1").
+			WithHeader("Content-Type", MediaTypeJSONPatch).
+			WithBytes([]byte(`[{"op": "test", "path": "/quantity", "value": 100}, {"op": "replace", "path": "/quantity", "value": 0}]`)).
+			Expect().
+			Status(http.StatusConflict)
+
+		// the failed patch left the product unchanged
+		e.GET("/api/v1/products/1").Expect().Status(http.StatusOK).JSON().Object().Value("quantity").Number().IsEqual(7)
+	})
+
+	testCases := []struct {
+		name           string
+		mediaType      string
+		patch          string
+		expectedStatus int
+	}{
+		{"Merge patch clearing a required field", MediaTypeMergePatch, `{"name": null}`, http.StatusBadRequest},
+		{"Merge patch with negative price", MediaTypeMergePatch, `{"price": -1}`, http.StatusBadRequest},
+		{"Merge patch with invalid barcode", MediaTypeMergePatch, `{"barcode": "4006381333932"}`, http.StatusBadRequest},
+		{"Merge patch changing status", MediaTypeMergePatch, `{"status": "draft"}`, http.StatusBadRequest},
+		{"Merge patch duplicating a SKU", MediaTypeMergePatch, `{"sku": "5678"}`, http.StatusConflict},
+		{"Malformed merge patch", MediaTypeMergePatch, `{"name": `, http.StatusBadRequest},
+		{"JSON patch on a missing path", MediaTypeJSONPatch, `[{"op": "remove", "path": "/nope"}]`, http.StatusBadRequest},
+		{"JSON patch with wrong value type", MediaTypeJSONPatch, `[{"op": "replace", "path": "/quantity", "value": "many"}]`, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.PATCH("/api/v1/products/1").
+				WithHeader("Content-Type", tc.mediaType).
+				WithBytes([]byte(tc.patch)).
+				Expect().
+				Status(tc.expectedStatus)
+		})
+	}
+
+	e.PATCH("/api/v1/products/1000000").
+		WithHeader("Content-Type", MediaTypeMergePatch).
+		WithBytes([]byte(`{"name": "x"}`)).
+		Expect().
+		Status(http.StatusNotFound)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyProductPatch(t *testing.T) {
+	doc := ProductCreateRequest{
+		Name:        "first product",
+		Description: "this describes the first product",
+		SKU:         "1234",
+		Price:       99.99,
+		Quantity:    1,
+		Tags:        []string{"sale", "eco"},
+		Type:        ProductTypeSimple,
+		Pricing:     PricingFixed,
+	}
+
+	var tests = []struct {
+		name      string
+		mediaType string
+		patch     string
+		want      func(ProductCreateRequest) ProductCreateRequest
+		wantErr   error
+	}{
+		{"merge patch sets fields", MediaTypeMergePatch, `{"name": "renamed", "quantity": 5}`,
+			func(d ProductCreateRequest) ProductCreateRequest { d.Name = "renamed"; d.Quantity = 5; return d }, nil},
+		{"merge patch null clears", MediaTypeMergePatch, `{"description": null, "tags": null}`,
+			func(d ProductCreateRequest) ProductCreateRequest { d.Description = ""; d.Tags = nil; return d }, nil},
+		{"merge patch not JSON", MediaTypeMergePatch, `{"name": `, nil, ErrInvalidPatch},
+		{"json patch replace and remove", MediaTypeJSONPatch,
+			`[{"op": "replace", "path": "/price", "value": 5}, {"op": "remove", "path": "/tags/0"}]`,
+			func(d ProductCreateRequest) ProductCreateRequest { d.Price = 5; d.Tags = []string{"eco"}; return d }, nil},
+		{"json patch passing test", MediaTypeJSONPatch,
+			`[{"op": "test", "path": "/quantity", "value": 1}, {"op": "replace", "path": "/quantity", "value": 0}]`,
+			func(d ProductCreateRequest) ProductCreateRequest { d.Quantity = 0; return d }, nil},
+		{"json patch failing test", MediaTypeJSONPatch,
+			`[{"op": "test", "path": "/quantity", "value": 2}, {"op": "replace", "path": "/quantity", "value": 0}]`,
+			nil, ErrPatchTestFailed},
+		{"json patch missing path", MediaTypeJSONPatch, `[{"op": "remove", "path": "/nope"}]`, nil, ErrInvalidPatch},
+		{"json patch not a list", MediaTypeJSONPatch, `{"op": "remove", "path": "/name"}`, nil, ErrInvalidPatch},
+		{"wrong value type", MediaTypeMergePatch, `{"quantity": "many"}`, nil, ErrInvalidPatch},
+		{"merge patch unknown field", MediaTypeMergePatch, `{"prise": 5}`, nil, ErrInvalidPatch},
+		{"json patch unknown field", MediaTypeJSONPatch, `[{"op": "add", "path": "/prise", "value": 5}]`, nil, ErrInvalidPatch},
+		{"unsupported media type", "application/json", `{}`, nil, ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyProductPatch(tt.mediaType, doc, []byte(tt.patch))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error incorrect. got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := tt.want(doc); !reflect.DeepEqual(got, want) {
+				t.Errorf("patched product incorrect. got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyProductPatchEmptyFields(t *testing.T) {
+	// every member of a product can be patched, even when the product leaves it empty
+	doc := ProductCreateRequest{
+		Name:    "bare product",
+		SKU:     "5678",
+		Price:   10,
+		Type:    ProductTypeSimple,
+		Pricing: PricingFixed,
+	}
+
+	var tests = []struct {
+		name      string
+		mediaType string
+		patch     string
+		want      func(ProductCreateRequest) ProductCreateRequest
+	}{
+		{"add to empty tags", MediaTypeJSONPatch, `[{"op": "add", "path": "/tags/-", "value": "new"}]`,
+			func(d ProductCreateRequest) ProductCreateRequest { d.Tags = []string{"new"}; return d }},
+		{"replace empty description", MediaTypeJSONPatch, `[{"op": "replace", "path": "/description", "value": "described"}]`,
+			func(d ProductCreateRequest) ProductCreateRequest { d.Description = "described"; return d }},
+		{"add to empty attributes", MediaTypeJSONPatch, `[{"op": "add", "path": "/attributes/voltage", "value": 230}]`,
+			func(d ProductCreateRequest) ProductCreateRequest {
+				d.Attributes = map[string]interface{}{"voltage": float64(230)}
+				return d
+			}},
+		{"test empty description", MediaTypeJSONPatch, `[{"op": "test", "path": "/description", "value": ""}]`,
+			func(d ProductCreateRequest) ProductCreateRequest { return d }},
+		{"test empty tags", MediaTypeJSONPatch, `[{"op": "test", "path": "/tags", "value": []}]`,
+			func(d ProductCreateRequest) ProductCreateRequest { return d }},
+		{"merge patch leaves empty fields omitted", MediaTypeMergePatch, `{"name": "renamed"}`,
+			func(d ProductCreateRequest) ProductCreateRequest { d.Name = "renamed"; return d }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyProductPatch(tt.mediaType, doc, []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := tt.want(doc); !reflect.DeepEqual(got, want) {
+				t.Errorf("patched product incorrect. got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaxExpandDepth is the longest relation path that can be expanded, e.g. suppliers.supplier.
+const MaxExpandDepth = 2
+
+// expandRelations whitelists the relation paths that can be expanded and maps them to their
+// preloads. Each preload runs as one query for all the products being read.
+var expandRelations = map[string]func(query *gorm.DB) *gorm.DB{
+	"media": func(query *gorm.DB) *gorm.DB {
+		return query.Preload("Media", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		})
+	},
+	"suppliers": func(query *gorm.DB) *gorm.DB {
+		return query.Preload("Suppliers", func(db *gorm.DB) *gorm.DB {
+			return db.Order("preferred DESC, cost_price ASC, supplier_id ASC")
+		})
+	},
+	"suppliers.supplier": func(query *gorm.DB) *gorm.DB {
+		return query.Preload("Suppliers.Supplier")
+	},
+}
+
+// Expansion is a list of relation paths to embed in product responses.
+type Expansion []string
+
+// ParseExpansion parses a comma-separated expand= value. Paths deeper than MaxExpandDepth and
+// relations that aren't whitelisted are rejected. Expanding a nested relation also expands
+// its parents.
+func ParseExpansion(value string) (Expansion, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var expansion Expansion
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		segments := strings.Split(path, ".")
+		if len(segments) > MaxExpandDepth {
+			return nil, fmt.Errorf("expand path %q is nested more than %d levels deep", path, MaxExpandDepth)
+		}
+		if _, ok := expandRelations[path]; !ok {
+			return nil, fmt.Errorf("unknown relation %q", path)
+		}
+
+		for i := range segments {
+			parent := strings.Join(segments[:i+1], ".")
+			if !containsString(expansion, parent) {
+				expansion = append(expansion, parent)
+			}
+		}
+	}
+	return expansion, nil
+}
+
+// Roots returns the top-level relations of the expansion, which are the product fields that
+// hold the embedded resources.
+func (e Expansion) Roots() []string {
+	var roots []string
+	for _, path := range e {
+		if !strings.Contains(path, ".") {
+			roots = append(roots, path)
+		}
+	}
+	return roots
+}
+
+// Apply adds the preloads for the expansion to query.
+func (e Expansion) Apply(query *gorm.DB) *gorm.DB {
+	for _, path := range e {
+		query = expandRelations[path](query)
+	}
+	return query
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExpansion(t *testing.T) {
+	var tests = []struct {
+		name    string
+		value   string
+		want    Expansion
+		wantErr bool
+	}{
+		{"empty", "", nil, false},
+		{"single relation", "media", Expansion{"media"}, false},
+		{"nested relation expands parent", "suppliers.supplier", Expansion{"suppliers", "suppliers.supplier"}, false},
+		{"duplicates are dropped", "media, suppliers,media,suppliers.supplier", Expansion{"media", "suppliers", "suppliers.supplier"}, false},
+		{"unknown relation", "media,warehouse", nil, true},
+		{"unknown nested relation", "media.product", nil, true},
+		{"too deep", "suppliers.supplier.products", nil, true},
+		{"empty relation", "media,", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpansion(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error incorrect. got %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expansion incorrect. got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpansionRoots(t *testing.T) {
+	got := Expansion{"media", "suppliers", "suppliers.supplier"}.Roots()
+	if want := []string{"media", "suppliers"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("roots incorrect. got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// productFieldColumns maps each field that can be requested with fields= to the columns needed
+// to produce it. Derived bundle fields also need the type and pricing of the product.
+var productFieldColumns = map[string][]string{
+	"id":          {"id"},
+	"name":        {"name"},
+	"description": {"description"},
+	"sku":         {"sku"},
+	"barcode":     {"barcode"},
+	"price":       {"price", "type", "pricing"},
+	"quantity":    {"quantity", "type", "pricing"},
+	"category":    {"category"},
+	"status":      {"status"},
+	"type":        {"type"},
+	"pricing":     {"pricing"},
+	"attributes":  {"attributes"},
+	"tags":        {"tags"},
+	"components":  {"type", "pricing"},
+	"margin":      {"price", "type", "pricing"},
+	"media":       {"id"},
+	"suppliers":   {"id"},
+	"created_at":  {"created_at"},
+	"created_by":  {"created_by"},
+	"updated_at":  {"updated_at"},
+	"updated_by":  {"updated_by"},
+	"deleted_at":  {"deleted_at"},
+}
+
+// FieldSet is a sparse fieldset of product fields. A nil FieldSet selects every field.
+type FieldSet []string
+
+// ParseFieldSet parses a comma-separated fields= value. Unknown fields are rejected and id is
+// always included. An empty value selects every field.
+func ParseFieldSet(value string) (FieldSet, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	fields := FieldSet{"id"}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if _, ok := productFieldColumns[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		if !fields.Has(field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// With returns the fieldset with fields added. Adding to a nil FieldSet, which already selects
+// every field, returns nil.
+func (f FieldSet) With(fields ...string) FieldSet {
+	if f == nil {
+		return nil
+	}
+	result := append(FieldSet{}, f...)
+	for _, field := range fields {
+		if !result.Has(field) {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// Has reports whether field is selected.
+func (f FieldSet) Has(field string) bool {
+	return f == nil || containsString(f, field)
+}
+
+// Columns returns the products columns to select for the fieldset, or nil for all columns.
+func (f FieldSet) Columns() []string {
+	if f == nil {
+		return nil
+	}
+
+	var columns []string
+	for _, field := range f {
+		for _, column := range productFieldColumns[field] {
+			if !containsString(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// Project returns the JSON form of product limited to the fieldset. Selected fields that are
+// omitted from the full JSON, such as the margin of a product without suppliers, stay omitted.
+func (f FieldSet) Project(product *Product) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(product)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]json.RawMessage, len(f))
+	for _, field := range f {
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldSet(t *testing.T) {
+	var tests = []struct {
+		name    string
+		value   string
+		want    FieldSet
+		wantErr bool
+	}{
+		{"empty selects everything", "", nil, false},
+		{"id is always included", "name,price", FieldSet{"id", "name", "price"}, false},
+		{"duplicates are dropped", "name,id,name", FieldSet{"id", "name"}, false},
+		{"whitespace is trimmed", "name, price", FieldSet{"id", "name", "price"}, false},
+		{"unknown field", "name,secret", nil, true},
+		{"empty field", "name,", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFieldSet(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error incorrect. got %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields incorrect. got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldSetColumns(t *testing.T) {
+	var tests = []struct {
+		name   string
+		fields FieldSet
+		want   []string
+	}{
+		{"all columns", nil, nil},
+		{"plain columns", FieldSet{"id", "name"}, []string{"id", "name"}},
+		{"derived fields need type and pricing", FieldSet{"id", "quantity"}, []string{"id", "pricing", "quantity", "type"}},
+		{"components are not a column", FieldSet{"id", "components"}, []string{"id", "pricing", "type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fields.Columns(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("columns incorrect. got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldSetProject(t *testing.T) {
+	product := Product{ID: 1, Name: "first product", Price: 9.99, Type: ProductTypeSimple}
+
+	projected, err := FieldSet{"id", "name", "margin"}.Project(&product)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{"id": "1", "name": `"first product"`}
+	if len(projected) != len(want) {
+		t.Fatalf("projected fields incorrect. got %v, want %v", projected, want)
+	}
+	for field, value := range want {
+		if string(projected[field]) != value {
+			t.Errorf("%s incorrect. got %s, want %s", field, projected[field], value)
+		}
+	}
+}
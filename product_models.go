@@ -7,34 +7,65 @@ import (
 )
 
 type Product struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"type:text;not null" json:"name"`
-	Description string         `gorm:"type:text" json:"description"`
-	SKU         string         `gorm:"type:varchar(128)" json:"sku"`
-	Price       float64        `gorm:"type:decimal(10,2);not null" json:"price"`
-	Quantity    int            `gorm:"type:int;not null" json:"quantity"`
-	Category    string         `gorm:"type:text" json:"category"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	Name        string            `gorm:"type:text;not null" json:"name"`
+	Description string            `gorm:"type:text" json:"description"`
+	SKU         string            `gorm:"type:varchar(128)" json:"sku"`
+	Barcode     string            `gorm:"type:varchar(14);not null;default:''" json:"barcode"`
+	Price       float64           `gorm:"type:decimal(10,2);not null" json:"price"`
+	Quantity    int               `gorm:"type:int;not null" json:"quantity"`
+	Category    string            `gorm:"type:text" json:"category"`
+	Status      string            `gorm:"type:varchar(16);not null;default:'active';index" json:"status"`
+	Type        string            `gorm:"type:varchar(16);not null;default:'simple'" json:"type"`
+	Pricing     string            `gorm:"type:varchar(16);not null;default:'fixed'" json:"pricing"`
+	Attributes  JSONMap           `gorm:"type:jsonb;not null;default:'{}'" json:"attributes"`
+	Tags        StringList        `gorm:"type:jsonb;not null;default:'[]'" json:"tags"`
+	Media       []ProductMedia    `gorm:"constraint:OnDelete:CASCADE" json:"media,omitempty"`
+	Components  []BundleComponent `gorm:"foreignKey:BundleID;constraint:OnDelete:CASCADE" json:"components,omitempty"`
+	Suppliers   []ProductSupplier `gorm:"constraint:OnDelete:CASCADE" json:"suppliers,omitempty"`
+	Margin      *ProductMargin    `gorm:"-" json:"margin,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	CreatedBy   string            `gorm:"type:text;not null;default:''" json:"created_by"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	UpdatedBy   string            `gorm:"type:text;not null;default:''" json:"updated_by"`
+	DeletedAt   gorm.DeletedAt    `gorm:"index" json:"deleted_at,omitempty"`
 }
 
+// ProductCreateRequest holds a new product. Bundles list their components and may leave out
+// the price when it is computed from the components.
 type ProductCreateRequest struct {
-	Name        string  `json:"name" validate:"required"`
-	Description string  `json:"description,omitempty"`
-	SKU         string  `json:"sku" validate:"required"`
-	Price       float64 `json:"price" validate:"required,gt=0"`
-	Quantity    int     `json:"quantity" validate:"min=0"`
-	Category    string  `json:"category,omitempty"`
+	Name        string                   `json:"name" validate:"required"`
+	Description string                   `json:"description,omitempty"`
+	SKU         string                   `json:"sku" validate:"required"`
+	Barcode     string                   `json:"barcode,omitempty" validate:"omitempty,gtin"`
+	Price       float64                  `json:"price" validate:"required_unless=Pricing computed,omitempty,gt=0"`
+	Quantity    int                      `json:"quantity" validate:"min=0"`
+	Category    string                   `json:"category,omitempty"`
+	Attributes  map[string]interface{}   `json:"attributes,omitempty"`
+	Tags        []string                 `json:"tags,omitempty" validate:"omitempty,dive,required,max=64"`
+	Status      string                   `json:"status,omitempty" validate:"omitempty,oneof=draft active"`
+	Type        string                   `json:"type,omitempty" validate:"omitempty,oneof=simple bundle"`
+	Pricing     string                   `json:"pricing,omitempty" validate:"omitempty,oneof=fixed computed"`
+	Components  []BundleComponentRequest `json:"components,omitempty" validate:"required_if=Type bundle,omitempty,max=50,dive"`
 }
 
+// ProductUpdateRequest holds the fields to change on PATCH. Tags replaces the product's tags;
+// AddTags and RemoveTags are applied afterwards. Status is changed through the transition
+// endpoints instead.
 type ProductUpdateRequest struct {
-	Name        *string  `json:"name,omitempty"`
-	Description *string  `json:"description,omitempty"`
-	SKU         *string  `json:"sku,omitempty"`
-	Price       *float64 `json:"price,omitempty" validate:"omitempty,gt=0"`
-	Quantity    *int     `json:"quantity,omitempty" validate:"omitempty,min=0"`
-	Category    *string  `json:"category,omitempty"`
+	Name        *string                  `json:"name,omitempty"`
+	Description *string                  `json:"description,omitempty"`
+	SKU         *string                  `json:"sku,omitempty"`
+	Barcode     *string                  `json:"barcode,omitempty" validate:"omitempty,len=0|gtin"`
+	Price       *float64                 `json:"price,omitempty" validate:"omitempty,gt=0"`
+	Quantity    *int                     `json:"quantity,omitempty" validate:"omitempty,min=0"`
+	Category    *string                  `json:"category,omitempty"`
+	Attributes  map[string]interface{}   `json:"attributes,omitempty"`
+	Tags        []string                 `json:"tags,omitempty" validate:"omitempty,dive,required,max=64"`
+	AddTags     []string                 `json:"add_tags,omitempty" validate:"omitempty,dive,required,max=64"`
+	RemoveTags  []string                 `json:"remove_tags,omitempty" validate:"omitempty,dive,required,max=64"`
+	Pricing     *string                  `json:"pricing,omitempty" validate:"omitempty,oneof=fixed computed"`
+	Components  []BundleComponentRequest `json:"components,omitempty" validate:"omitempty,max=50,dive"`
 }
 
 type BulkProductResponse struct {
@@ -44,3 +75,66 @@ type BulkProductResponse struct {
 	TotalPages int64     `json:"total_pages"`
 	TotalCount int64     `json:"total_count"`
 }
+
+// ProductLookupRequest asks for many products at once by SKU and/or id. Each list may hold
+// up to 100 entries.
+type ProductLookupRequest struct {
+	SKUs []string `json:"skus,omitempty" validate:"required_without=IDs,max=100,dive,required"`
+	IDs  []int    `json:"ids,omitempty" validate:"required_without=SKUs,max=100,dive,gt=0"`
+}
+
+// ProductLookupResponse holds the products found by a lookup, ordered by id, and the requested
+// SKUs and ids that didn't match a non-deleted product.
+type ProductLookupResponse struct {
+	Products    []Product `json:"products"`
+	MissingSKUs []string  `json:"missing_skus"`
+	MissingIDs  []int     `json:"missing_ids"`
+}
+
+// ProductReadOptions shape the products returned by the read endpoints.
+type ProductReadOptions struct {
+	// Fields limits the returned fields; nil returns every field.
+	Fields FieldSet
+	// Expand lists the relations to embed.
+	Expand Expansion
+}
+
+// ProductStats aggregates a set of products. Bundles count towards Count and the prices, but
+// as their stock is derived from their components they add nothing to InventoryValue and are
+// never counted as out of stock.
+type ProductStats struct {
+	Count          int64   `json:"count"`
+	InventoryValue float64 `json:"inventory_value"`
+	MinPrice       float64 `json:"min_price"`
+	MaxPrice       float64 `json:"max_price"`
+	AvgPrice       float64 `json:"avg_price"`
+	OutOfStock     int64   `json:"out_of_stock"`
+}
+
+type CategoryStats struct {
+	Category string `json:"category"`
+	ProductStats
+}
+
+type ProductStatsResponse struct {
+	ProductStats
+	Categories []CategoryStats `json:"categories"`
+}
+
+// ProductFilter narrows the products returned by GetProducts.
+type ProductFilter struct {
+	// Attributes holds exact-match filters on custom attribute values, keyed by attribute name.
+	Attributes map[string]string
+	// TagsAny matches products carrying at least one of the tags; TagsAll those carrying every tag.
+	TagsAny []string
+	TagsAll []string
+	// Statuses limits the result to products in one of the statuses; empty means any status.
+	Statuses []string
+	// Expression is a parsed filter= expression, or nil.
+	Expression FilterExpr
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,47 @@
+package main
+
+import "fmt"
+
+const (
+	StatusDraft        = "draft"
+	StatusActive       = "active"
+	StatusDiscontinued = "discontinued"
+	StatusArchived     = "archived"
+)
+
+// productStatuses lists every status in lifecycle order.
+var productStatuses = []string{StatusDraft, StatusActive, StatusDiscontinued, StatusArchived}
+
+// productTransitions lists the statuses each status may move to. Archived is terminal.
+var productTransitions = map[string][]string{
+	StatusDraft:        {StatusActive, StatusArchived},
+	StatusActive:       {StatusDiscontinued, StatusArchived},
+	StatusDiscontinued: {StatusActive, StatusArchived},
+	StatusArchived:     {},
+}
+
+// transitionTargets maps the transition endpoints to the status they move a product to.
+var transitionTargets = map[string]string{
+	"activate":    StatusActive,
+	"discontinue": StatusDiscontinued,
+	"archive":     StatusArchived,
+}
+
+func IsValidStatus(status string) bool {
+	_, ok := productTransitions[status]
+	return ok
+}
+
+func CanTransition(from, to string) bool {
+	return containsString(productTransitions[from], to)
+}
+
+// TransitionError reports an illegal status change.
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot transition product from %s to %s", e.From, e.To)
+}
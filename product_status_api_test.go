@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestProductLifecycle(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	products := getSampleProductRequests()
+	products[0].Status = StatusDraft
+	for _, product := range products {
+		e.POST("/api/v1/products").WithJSON(product).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().Object().Value("status").String().NotEmpty()
+	}
+
+	// products can't be created directly in a later status
+	invalid := ProductCreateRequest{Name: "archived", SKU: "s1", Price: 1, Status: StatusArchived}
+	e.POST("/api/v1/products").WithJSON(invalid).Expect().Status(http.StatusBadRequest)
+
+	// the public list only shows active products by default
+	e.GET("/api/v1/products").Expect().Status(http.StatusOK).
+		JSON().Object().Value("total_count").Number().IsEqual(2)
+	e.GET("/api/v1/products").WithQuery("status", "draft").Expect().Status(http.StatusOK).
+		JSON().Object().Value("total_count").Number().IsEqual(1)
+	e.GET("/api/v1/products").WithQuery("status", "all").Expect().Status(http.StatusOK).
+		JSON().Object().Value("total_count").Number().IsEqual(3)
+	e.GET("/api/v1/products").WithQuery("status", "bogus").Expect().Status(http.StatusBadRequest)
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		productStatus  string
+	}{
+		{"Draft to discontinued", "/api/v1/products/1/discontinue", http.StatusBadRequest, ""},
+		{"Draft to active", "/api/v1/products/1/activate", http.StatusOK, StatusActive},
+		{"Active to discontinued", "/api/v1/products/1/discontinue", http.StatusOK, StatusDiscontinued},
+		{"Discontinued to discontinued", "/api/v1/products/1/discontinue", http.StatusBadRequest, ""},
+		{"Discontinued to archived", "/api/v1/products/1/archive", http.StatusOK, StatusArchived},
+		{"Archived to active", "/api/v1/products/1/activate", http.StatusBadRequest, ""},
+		{"Unknown transition", "/api/v1/products/1/publish", http.StatusNotFound, ""},
+		{"Missing product", "/api/v1/products/1000000/archive", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := e.POST(tc.path).Expect().Status(tc.expectedStatus)
+			if tc.expectedStatus == http.StatusOK {
+				response.JSON().Object().Value("status").String().IsEqual(tc.productStatus)
+			}
+		})
+	}
+
+	e.GET("/api/v1/products/1").Expect().Status(http.StatusOK).
+		JSON().Object().Value("status").String().IsEqual(StatusArchived)
+}
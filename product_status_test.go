@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	var tests = []struct {
+		from    string
+		to      string
+		allowed bool
+	}{
+		{StatusDraft, StatusActive, true},
+		{StatusDraft, StatusArchived, true},
+		{StatusDraft, StatusDiscontinued, false},
+		{StatusActive, StatusDiscontinued, true},
+		{StatusActive, StatusArchived, true},
+		{StatusActive, StatusDraft, false},
+		{StatusActive, StatusActive, false},
+		{StatusDiscontinued, StatusActive, true},
+		{StatusDiscontinued, StatusArchived, true},
+		{StatusDiscontinued, StatusDraft, false},
+		{StatusArchived, StatusDraft, false},
+		{StatusArchived, StatusActive, false},
+		{"unknown", StatusActive, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to); got != tt.allowed {
+				t.Errorf("transition incorrect. got %v, want %v", got, tt.allowed)
+			}
+		})
+	}
+}
@@ -4,11 +4,11 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strconv"
 	"testing"
 
 	"github.com/gavv/httpexpect/v2"
-	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -266,6 +266,11 @@ func TestGetProducts(t *testing.T) {
 			queryParams:    map[string]string{"page": "2", "size": "-2"},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:           "Invalid - size above the maximum",
+			queryParams:    map[string]string{"size": "101"},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -456,21 +461,57 @@ func TestDeleteProducts(t *testing.T) {
 	}
 }
 
+// initRouter builds the router with authentication off, so tests can focus on the handlers;
+// auth_api_test.go covers authentication.
 func initRouter() (*mux.Router, *zap.Logger, *gorm.DB) {
+	return initRouterWith(func(cfg *Config) { cfg.Auth.Enabled = false })
+}
+
+func initRouterWith(configure func(*Config)) (*mux.Router, *zap.Logger, *gorm.DB) {
 	logger, err := zap.NewDevelopment()
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	zap.ReplaceGlobals(logger)
 
-	db := InitDatabase()
+	cfg, err := LoadConfig("")
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	configure(&cfg)
+
+	db := InitDatabase(cfg.Database)
+	if err := InstrumentTracing(db); err != nil {
+		log.Fatalf("Failed to instrument database tracing: %v", err)
+	}
+	if err := RecordActors(db); err != nil {
+		log.Fatalf("Failed to record actors: %v", err)
+	}
 	CleanDatabase(db)
 
-	service := NewProductService(db)
-	validator := validator.New()
-	handler := NewProductHandler(service, validator)
+	blobs, err := NewLocalBlobStore(os.TempDir() + "/simpler-test-media")
+	if err != nil {
+		log.Fatalf("Failed to initialize blob store: %v", err)
+	}
+
+	service := NewProductService(db, blobs, cfg.Media)
+	validator := NewValidator()
+	handler := NewProductHandler(service, validator, cfg.Pagination)
+	supplierHandler := NewSupplierHandler(NewSupplierService(db), validator)
+	apiKeyService := NewAPIKeyService(db)
+	apiKeyHandler := NewAPIKeyHandler(apiKeyService, validator)
+	tokens, err := NewTokenVerifier(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Failed to initialize token verification: %v", err)
+	}
+
+	health := NewHealthHandler(db, cfg.Database.PingTimeout)
+	metrics := NewMetrics()
+	if err := metrics.InstrumentDatabase(db); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
 
-	return InitRouter(handler), logger, db
+	return InitRouter(handler, supplierHandler, apiKeyHandler, NewAuthenticator(cfg.Auth, apiKeyService, tokens), health, metrics, cfg.Server), logger, db
 }
 
 func getSampleProductRequests() []ProductCreateRequest {
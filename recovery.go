@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+// Recover turns a panic in a handler into a 500 response and logs it with its stack, instead
+// of letting net/http drop the connection. http.ErrAbortHandler is re-panicked since it is the
+// sanctioned way to abort a response.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			if value == http.ErrAbortHandler {
+				panic(value)
+			}
+
+			LoggerFromContext(r.Context()).Error("Request panicked",
+				zap.Any("panic", value),
+				zap.ByteString("stack", debug.Stack()))
+			if recorder.wroteHeader {
+				// Part of the response is already on the wire, so the client can only be told
+				// by cutting it short.
+				panic(http.ErrAbortHandler)
+			}
+			http.Error(recorder, "unexpected error occurred", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRecover(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	handler := RequestLogging(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var product *Product
+		w.Header().Set("Content-Type", "application/json")
+		_ = product.Name
+	})))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/products/1", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("status incorrect. got %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+	if body := recorder.Body.String(); body != "unexpected error occurred\n" {
+		t.Errorf("body incorrect. got %q", body)
+	}
+
+	panics := logs.FilterMessage("Request panicked").All()
+	if len(panics) != 1 {
+		t.Fatalf("expected one panic log entry. got %d", len(panics))
+	}
+	fields := panics[0].ContextMap()
+	if fields["request_id"] != recorder.Header().Get(RequestIDHeader) {
+		t.Errorf("panic not logged with the request logger. got %v", fields)
+	}
+	if stack, _ := fields["stack"].(string); !strings.Contains(stack, "recovery_test.go") {
+		t.Errorf("stack missing the panicking frame. got %q", stack)
+	}
+	if completed := logs.FilterMessage("Request completed").All(); len(completed) != 1 || completed[0].ContextMap()["status"] != int64(http.StatusInternalServerError) {
+		t.Errorf("expected the request to be logged as a 500. got %v", completed)
+	}
+}
+
+func TestRecoverAbortsStartedResponse(t *testing.T) {
+	core, _ := observer.New(zapcore.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("halfway through")
+	}))
+
+	defer func() {
+		if value := recover(); value != http.ErrAbortHandler {
+			t.Errorf("expected the response to be aborted. got %v", value)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestReplaceProduct(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	requests := getSampleProductRequests()
+	requests[0].Tags = []string{"sale"}
+	requests[0].Barcode = "4006381333931"
+	requests[0].Status = StatusDraft
+	for _, req := range requests {
+		e.POST("/api/v1/products").WithJSON(req).Expect().Status(http.StatusCreated)
+	}
+
+	// fields left out of the document are cleared, status is kept
+	replaced := e.PUT("/api/v1/products/1").
+		WithJSON(ProductCreateRequest{Name: "replaced", SKU: "1234", Price: 12.5}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	replaced.Value("name").String().IsEqual("replaced")
+	replaced.Value("description").String().IsEqual("")
+	replaced.Value("barcode").String().IsEqual("")
+	replaced.Value("quantity").Number().IsEqual(0)
+	replaced.Value("tags").Array().IsEmpty()
+	replaced.Value("status").String().IsEqual(StatusDraft)
+
+	e.GET("/api/v1/products/1").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("description").String().IsEqual("")
+
+	testCases := []struct {
+		name           string
+		path           string
+		request        ProductCreateRequest
+		expectedStatus int
+	}{
+		{"Missing SKU", "/api/v1/products/1", ProductCreateRequest{Name: "x", Price: 1}, http.StatusBadRequest},
+		{"Missing price", "/api/v1/products/1", ProductCreateRequest{Name: "x", SKU: "1234"}, http.StatusBadRequest},
+		{"Type change", "/api/v1/products/1", ProductCreateRequest{Name: "x", SKU: "1234", Type: ProductTypeBundle,
+			Pricing: PricingComputed, Components: []BundleComponentRequest{{ComponentID: 2, Quantity: 1}}}, http.StatusBadRequest},
+		{"Status", "/api/v1/products/1", ProductCreateRequest{Name: "x", SKU: "1234", Price: 1, Status: StatusActive}, http.StatusBadRequest},
+		{"Duplicate SKU", "/api/v1/products/1", ProductCreateRequest{Name: "x", SKU: "5678", Price: 1}, http.StatusConflict},
+		{"Unknown product", "/api/v1/products/1000000", ProductCreateRequest{Name: "x", SKU: "x", Price: 1}, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.PUT(tc.path).WithJSON(tc.request).
+				Expect().
+				Status(tc.expectedStatus)
+		})
+	}
+
+	// the rejected status change left the product as it was
+	e.GET("/api/v1/products/1").Expect().Status(http.StatusOK).
+		JSON().Object().Value("status").String().IsEqual(StatusDraft)
+}
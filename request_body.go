@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const MediaTypeJSON = "application/json"
+
+// RequestBodyError reports a request body that could not be decoded. Field names the JSON
+// member at fault, or is empty when the body as a whole is malformed.
+type RequestBodyError struct {
+	Field   string
+	Message string
+}
+
+func (e *RequestBodyError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// LimitBody caps request bodies at maxBytes; reading past the limit fails with an
+// *http.MaxBytesError, which writeBodyError answers with 413. Multipart bodies are left to
+// the media upload handler, which applies a larger limit of its own.
+func LimitBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireContentType wraps h so that a request carrying a body must declare one of
+// mediaTypes, answering 415 otherwise. Requests without a body are passed through.
+func requireContentType(h http.HandlerFunc, mediaTypes ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength == 0 {
+			h(w, r)
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err == nil && containsString(mediaTypes, mediaType) {
+			h(w, r)
+			return
+		}
+
+		LoggerFromContext(r.Context()).Info("Rejected request with unsupported content type", zap.String("content type", r.Header.Get("Content-Type")))
+		if r.Method == http.MethodPatch {
+			w.Header().Set("Accept-Patch", strings.Join(mediaTypes, ", "))
+		}
+		if len(mediaTypes) == 1 {
+			http.Error(w, "Content-Type must be "+mediaTypes[0], http.StatusUnsupportedMediaType)
+			return
+		}
+		http.Error(w, "Content-Type must be one of "+strings.Join(mediaTypes, ", "), http.StatusUnsupportedMediaType)
+	}
+}
+
+// decodeJSON strictly decodes a single JSON value from body into v: unknown members, values
+// of the wrong type and trailing data are reported as a *RequestBodyError.
+func decodeJSON(body io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return jsonDecodeError(err)
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return err
+		}
+		return &RequestBodyError{Message: "request body must contain a single JSON value"}
+	}
+	return nil
+}
+
+func jsonDecodeError(err error) error {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesError):
+		return err
+	case errors.Is(err, io.EOF):
+		return &RequestBodyError{Message: "request body must not be empty"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &RequestBodyError{Message: "request body contains malformed JSON"}
+	case errors.As(err, &syntaxError):
+		return &RequestBodyError{Message: fmt.Sprintf("request body contains malformed JSON at offset %d", syntaxError.Offset)}
+	case errors.As(err, &typeError):
+		if typeError.Field == "" {
+			return &RequestBodyError{Message: "request body must be " + jsonTypeName(typeError.Type.Kind())}
+		}
+		return &RequestBodyError{Field: typeError.Field, Message: "must be " + jsonTypeName(typeError.Type.Kind())}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &RequestBodyError{Field: field, Message: "unknown field"}
+	default:
+		return err
+	}
+}
+
+// jsonTypeName describes a Go kind in JSON terms.
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// writeBodyError writes the response for an error returned while reading or decoding a
+// request body: 413 past the size limit, 400 for malformed JSON and 500 otherwise. Errors
+// about a single member are reported in the same shape as validation errors.
+func writeBodyError(w http.ResponseWriter, r *http.Request, action string, err error) {
+	logger := LoggerFromContext(r.Context())
+	var maxBytesError *http.MaxBytesError
+	var bodyError *RequestBodyError
+	switch {
+	case errors.As(err, &maxBytesError):
+		logger.Info("Failed to "+action+" because request body was too large", zap.Int64("limit", maxBytesError.Limit))
+		http.Error(w, fmt.Sprintf("request body must not exceed %d bytes", maxBytesError.Limit), http.StatusRequestEntityTooLarge)
+	case errors.As(err, &bodyError) && bodyError.Field != "":
+		logger.Info("Failed to "+action+" because request could not be decoded", zap.Error(err))
+		httpBadRequest(w, map[string]string{bodyError.Field: bodyError.Message})
+	case errors.As(err, &bodyError):
+		logger.Info("Failed to "+action+" because request could not be decoded", zap.Error(err))
+		http.Error(w, bodyError.Message, http.StatusBadRequest)
+	default:
+		logger.Error("Failed to "+action+" because request body could not be read", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+	}
+}
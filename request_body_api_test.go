@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestRequestBodies(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	e.POST("/api/v1/products").WithJSON(getSampleProductRequests()[0]).Expect().Status(http.StatusCreated)
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		{"unknown field", http.MethodPost, "/api/v1/products", MediaTypeJSON, `{"name": "lamp", "sku": "l1", "prise": 5}`, http.StatusBadRequest},
+		{"wrong type", http.MethodPost, "/api/v1/products", MediaTypeJSON, `{"name": "lamp", "sku": "l1", "price": "5"}`, http.StatusBadRequest},
+		{"trailing data", http.MethodPost, "/api/v1/products", MediaTypeJSON, `{"name": "lamp", "sku": "l1", "price": 5} {}`, http.StatusBadRequest},
+		{"unknown field in an update", http.MethodPatch, "/api/v1/products/1", MediaTypeJSON, `{"prise": 5}`, http.StatusBadRequest},
+		{"unknown field in a merge patch", http.MethodPatch, "/api/v1/products/1", MediaTypeMergePatch, `{"prise": 5}`, http.StatusBadRequest},
+		{"unknown field in a supplier", http.MethodPost, "/api/v1/suppliers", MediaTypeJSON, `{"name": "Acme", "emial": "a@acme.test"}`, http.StatusBadRequest},
+		{"form content type", http.MethodPost, "/api/v1/products", "application/x-www-form-urlencoded", `name=lamp`, http.StatusUnsupportedMediaType},
+		{"text patch", http.MethodPatch, "/api/v1/products/1", "text/plain", `{"name": "lamp"}`, http.StatusUnsupportedMediaType},
+		{"json media upload", http.MethodPost, "/api/v1/products/1/media", MediaTypeJSON, `{}`, http.StatusUnsupportedMediaType},
+		{"too large", http.MethodPost, "/api/v1/products", MediaTypeJSON, `{"name": "` + strings.Repeat("a", 1<<20) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.Request(tc.method, tc.path).
+				WithHeader("Content-Type", tc.contentType).
+				WithBytes([]byte(tc.body)).
+				Expect().
+				Status(tc.expectedStatus)
+		})
+	}
+
+	e.POST("/api/v1/products").
+		WithHeader("Content-Type", MediaTypeJSON).
+		WithBytes([]byte(`{"name": "lamp", "sku": "l1", "prise": 5}`)).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().Object().IsEqual(map[string]string{"prise": "unknown field"})
+
+	e.PATCH("/api/v1/products/1").
+		WithHeader("Content-Type", "text/plain").
+		WithBytes([]byte(`{}`)).
+		Expect().
+		Status(http.StatusUnsupportedMediaType).
+		Header("Accept-Patch").IsEqual("application/json, application/merge-patch+json, application/json-patch+json")
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	type request struct {
+		Name       string  `json:"name"`
+		Price      float64 `json:"price"`
+		Dimensions struct {
+			Width int `json:"width"`
+		} `json:"dimensions"`
+	}
+
+	var tests = []struct {
+		name string
+		body string
+		want *RequestBodyError
+	}{
+		{"valid", `{"name": "lamp", "price": 9.5}`, nil},
+		{"trailing whitespace", "{\"name\": \"lamp\"}\n", nil},
+		{"unknown field", `{"name": "lamp", "prise": 9.5}`, &RequestBodyError{Field: "prise", Message: "unknown field"}},
+		{"wrong type", `{"price": "cheap"}`, &RequestBodyError{Field: "price", Message: "must be a number"}},
+		{"wrong nested type", `{"dimensions": {"width": 1.5}}`, &RequestBodyError{Field: "dimensions.width", Message: "must be an integer"}},
+		{"not an object", `["lamp"]`, &RequestBodyError{Message: "request body must be an object"}},
+		{"trailing value", `{"name": "lamp"} {"name": "desk"}`, &RequestBodyError{Message: "request body must contain a single JSON value"}},
+		{"trailing garbage", `{"name": "lamp"}}`, &RequestBodyError{Message: "request body must contain a single JSON value"}},
+		{"empty", ``, &RequestBodyError{Message: "request body must not be empty"}},
+		{"truncated", `{"name": "la`, &RequestBodyError{Message: "request body contains malformed JSON"}},
+		{"malformed", `{"name" "lamp"}`, &RequestBodyError{Message: "request body contains malformed JSON at offset 9"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got request
+			err := decodeJSON(strings.NewReader(tt.body), &got)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var bodyError *RequestBodyError
+			if !errors.As(err, &bodyError) || !reflect.DeepEqual(bodyError, tt.want) {
+				t.Errorf("error incorrect. got %#v, want %#v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteBodyError(t *testing.T) {
+	var tests = []struct {
+		name   string
+		err    error
+		status int
+		body   string
+	}{
+		{"too large", &http.MaxBytesError{Limit: 10}, http.StatusRequestEntityTooLarge, "request body must not exceed 10 bytes\n"},
+		{"field", &RequestBodyError{Field: "prise", Message: "unknown field"}, http.StatusBadRequest, "{\"prise\":\"unknown field\"}\n"},
+		{"whole body", &RequestBodyError{Message: "request body must not be empty"}, http.StatusBadRequest, "request body must not be empty\n"},
+		{"read failure", errors.New("connection reset"), http.StatusInternalServerError, "unexpected error occurred\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			writeBodyError(recorder, httptest.NewRequest(http.MethodPost, "/", nil), "create product", tt.err)
+			if recorder.Code != tt.status || recorder.Body.String() != tt.body {
+				t.Errorf("response incorrect. got %d %q, want %d %q", recorder.Code, recorder.Body.String(), tt.status, tt.body)
+			}
+		})
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	handler := LimitBody(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]interface{}
+		if err := decodeJSON(r.Body, &v); err != nil {
+			writeBodyError(w, r, "decode", err)
+		}
+	}))
+
+	var tests = []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"within the limit", MediaTypeJSON, `{"a": 1}`, http.StatusOK},
+		{"over the limit", MediaTypeJSON, `{"a": "0123456789abcdef"}`, http.StatusRequestEntityTooLarge},
+		{"multipart is exempt", "multipart/form-data; boundary=x", `{"a": "0123456789abcdef"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", tt.contentType)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.status {
+				t.Errorf("status incorrect. got %d, want %d", recorder.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestRequireContentType(t *testing.T) {
+	handler := requireContentType(func(w http.ResponseWriter, r *http.Request) {}, MediaTypeJSON, MediaTypeMergePatch)
+
+	var tests = []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+		acceptPatch string
+	}{
+		{"json", http.MethodPost, "application/json; charset=utf-8", `{}`, http.StatusOK, ""},
+		{"alternative type", http.MethodPatch, MediaTypeMergePatch, `{}`, http.StatusOK, ""},
+		{"no body", http.MethodPost, "", "", http.StatusOK, ""},
+		{"missing type", http.MethodPost, "", `{}`, http.StatusUnsupportedMediaType, ""},
+		{"wrong type", http.MethodPost, "text/plain", `{}`, http.StatusUnsupportedMediaType, ""},
+		{"wrong patch type", http.MethodPatch, "text/plain", `{}`, http.StatusUnsupportedMediaType, "application/json, application/merge-patch+json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != tt.status {
+				t.Errorf("status incorrect. got %d, want %d", recorder.Code, tt.status)
+			}
+			if got := recorder.Header().Get("Accept-Patch"); got != tt.acceptPatch {
+				t.Errorf("Accept-Patch incorrect. got %q, want %q", got, tt.acceptPatch)
+			}
+		})
+	}
+}
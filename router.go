@@ -7,18 +7,78 @@ import (
 	"go.uber.org/zap"
 )
 
-func InitRouter(handler *ProductHandler) *mux.Router {
+func InitRouter(handler *ProductHandler, supplierHandler *SupplierHandler, apiKeyHandler *APIKeyHandler, auth *Authenticator, health *HealthHandler, metrics *Metrics, cfg ServerConfig) *mux.Router {
+	timeouts := NewRouteTimeouts(cfg)
+
 	router := mux.NewRouter()
+	router.Use(Tracing, RequestLogging, metrics.Middleware, Recover)
+	router.NotFoundHandler = Tracing(RequestLogging(metrics.Middleware(http.NotFoundHandler())))
+	router.MethodNotAllowedHandler = Tracing(RequestLogging(metrics.Middleware(http.HandlerFunc(methodNotAllowed))))
+
 	router.HandleFunc("/health", HealthCheckHandler).Methods(http.MethodGet)
+	router.HandleFunc("/health/live", health.Live).Methods(http.MethodGet)
+	router.HandleFunc("/health/ready", health.Ready).Methods(http.MethodGet)
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
 
 	apiRouter := router.PathPrefix("/api/v1").Subrouter()
+	apiRouter.Use(LimitBody(int64(cfg.MaxBodyBytes)), timeouts.Middleware, auth.Middleware)
+
+	// Reads need products:read and changes products:write. Deleting products and suppliers,
+	// changing the attribute schema and managing API keys need products:admin.
+	read := func(h http.HandlerFunc) http.HandlerFunc { return requireScope(ScopeProductsRead, h) }
+	write := func(h http.HandlerFunc) http.HandlerFunc { return requireScope(ScopeProductsWrite, h) }
+	admin := func(h http.HandlerFunc) http.HandlerFunc { return requireScope(ScopeProductsAdmin, h) }
+
+	jsonBody := func(h http.HandlerFunc) http.HandlerFunc { return requireContentType(h, MediaTypeJSON) }
+	patchBody := func(h http.HandlerFunc) http.HandlerFunc {
+		return requireContentType(h, MediaTypeJSON, MediaTypeMergePatch, MediaTypeJSONPatch)
+	}
+
+	apiRouter.HandleFunc("/products", write(jsonBody(handler.CreateProduct))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/products", read(handler.GetProducts)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}", read(handler.GetProduct)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/by-barcode/{code}", read(handler.GetProductByBarcode)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}", write(jsonBody(handler.ReplaceProduct))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}", write(patchBody(handler.UpdateProduct))).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}", admin(handler.DeleteProduct)).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/products/sku/{sku}", read(handler.GetProduct)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/sku/{sku}", write(jsonBody(handler.PutProductBySKU))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/products/sku/{sku}", write(patchBody(handler.UpdateProduct))).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/products/sku/{sku}", admin(handler.DeleteProduct)).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/products/lookup", read(jsonBody(handler.LookupProducts))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/products/stats", read(handler.GetProductStats)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/{transition:activate|discontinue|archive}", write(handler.TransitionProduct)).Methods(http.MethodPost)
+
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/media", write(requireContentType(handler.UploadProductMedia, "multipart/form-data"))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/media", read(handler.GetProductMediaList)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/media/{mediaId:[0-9]+}", read(handler.GetProductMedia)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/media/{mediaId:[0-9]+}", write(jsonBody(handler.UpdateProductMedia))).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/media/{mediaId:[0-9]+}", write(handler.DeleteProductMedia)).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/media/{mediaId:[0-9]+}/content", read(handler.GetProductMediaContent)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/media/{mediaId:[0-9]+}/thumbnail", read(handler.GetProductMediaThumbnail)).Methods(http.MethodGet)
 
-	apiRouter.HandleFunc("/products", handler.CreateProduct).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/products", handler.GetProducts).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/products/{id:[0-9]+}", handler.GetProduct).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/products/{id:[0-9]+}", handler.UpdateProduct).Methods(http.MethodPatch)
-	apiRouter.HandleFunc("/products/{id:[0-9]+}", handler.DeleteProduct).Methods(http.MethodDelete)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/suppliers", read(supplierHandler.GetProductSuppliers)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/suppliers/{supplierId:[0-9]+}", write(jsonBody(supplierHandler.SetProductSupplier))).Methods(http.MethodPut)
+	apiRouter.HandleFunc("/products/{id:[0-9]+}/suppliers/{supplierId:[0-9]+}", write(supplierHandler.DeleteProductSupplier)).Methods(http.MethodDelete)
 
+	apiRouter.HandleFunc("/suppliers", write(jsonBody(supplierHandler.CreateSupplier))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/suppliers", read(supplierHandler.GetSuppliers)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/suppliers/{id:[0-9]+}", read(supplierHandler.GetSupplier)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/suppliers/{id:[0-9]+}", write(jsonBody(supplierHandler.UpdateSupplier))).Methods(http.MethodPatch)
+	apiRouter.HandleFunc("/suppliers/{id:[0-9]+}", admin(supplierHandler.DeleteSupplier)).Methods(http.MethodDelete)
+
+	apiRouter.HandleFunc("/tags", read(handler.GetTags)).Methods(http.MethodGet)
+
+	apiRouter.HandleFunc("/attribute-definitions", admin(jsonBody(handler.CreateAttributeDefinition))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/attribute-definitions", read(handler.GetAttributeDefinitions)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/attribute-definitions/{id:[0-9]+}", admin(handler.DeleteAttributeDefinition)).Methods(http.MethodDelete)
+
+	apiRouter.HandleFunc("/api-keys", admin(jsonBody(apiKeyHandler.CreateAPIKey))).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/api-keys", admin(apiKeyHandler.GetAPIKeys)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/api-keys/{id:[0-9]+}/rotate", admin(apiKeyHandler.RotateAPIKey)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/api-keys/{id:[0-9]+}", admin(apiKeyHandler.RevokeAPIKey)).Methods(http.MethodDelete)
+
+	timeouts.warnUnknownRoutes(router)
 	zap.L().Info("Router initialized successfully")
 	return router
 }
@@ -27,3 +87,7 @@ func HealthCheckHandler(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
+
+func methodNotAllowed(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func NewServer(cfg ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// RunServer serves on listener until ctx is cancelled. It then calls onShutdown (when not nil),
+// keeps serving for cfg.ShutdownDelay, stops accepting connections and waits up to
+// cfg.ShutdownTimeout for in-flight requests to finish. It returns nil after a clean shutdown,
+// or the error that stopped the server or the deadline error if requests were cut off.
+func RunServer(ctx context.Context, server *http.Server, listener net.Listener, cfg ServerConfig, onShutdown func()) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	zap.L().Info("Shutting down server", zap.Duration("delay", cfg.ShutdownDelay), zap.Duration("timeout", cfg.ShutdownTimeout))
+	if onShutdown != nil {
+		onShutdown()
+	}
+	if cfg.ShutdownDelay > 0 {
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		zap.L().Warn("Server did not drain in time, closing remaining connections", zap.Error(err))
+		server.Close()
+		return err
+	}
+
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	zap.L().Info("Server stopped")
+	return nil
+}
+
+// CloseDatabase closes the connection pool once the server has stopped using it.
+func CloseDatabase(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		zap.L().Error("Failed to access database pool", zap.Error(err))
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		zap.L().Error("Failed to close database pool", zap.Error(err))
+		return
+	}
+	zap.L().Info("Database connection closed")
+}
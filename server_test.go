@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunServer(t *testing.T) {
+	var tests = []struct {
+		name            string
+		handlerDelay    time.Duration
+		shutdownTimeout time.Duration
+		drained         bool
+	}{
+		{"drains in-flight requests", 100 * time.Millisecond, 5 * time.Second, true},
+		{"cuts off requests past the deadline", 5 * time.Second, 100 * time.Millisecond, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-time.After(tt.handlerDelay):
+					w.Write([]byte("done"))
+				case <-r.Context().Done():
+				}
+			})
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			runErr := make(chan error, 1)
+			go func() {
+				cfg := DefaultConfig().Server
+				cfg.ShutdownTimeout = tt.shutdownTimeout
+				runErr <- RunServer(ctx, NewServer(cfg, handler), listener, cfg, nil)
+			}()
+
+			type result struct {
+				body string
+				err  error
+			}
+			response := make(chan result, 1)
+			go func() {
+				resp, err := http.Get("http://" + listener.Addr().String())
+				if err != nil {
+					response <- result{err: err}
+					return
+				}
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				response <- result{string(body), err}
+			}()
+
+			<-started
+			cancel()
+
+			err = <-runErr
+			got := <-response
+			if tt.drained {
+				if err != nil {
+					t.Errorf("unexpected shutdown error: %v", err)
+				}
+				if got.err != nil || got.body != "done" {
+					t.Errorf("in-flight request should complete. got %q, %v", got.body, got.err)
+				}
+			} else {
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("expected a deadline error. got %v", err)
+				}
+				if got.err == nil && got.body == "done" {
+					t.Errorf("request past the deadline should be cut off")
+				}
+			}
+
+			if _, err := net.DialTimeout("tcp", listener.Addr().String(), time.Second); err == nil {
+				t.Errorf("server should stop accepting connections")
+			}
+		})
+	}
+}
+
+func TestRunServerShutdownDelay(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	var notified atomic.Bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if notified.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+
+	cfg := DefaultConfig().Server
+	cfg.ShutdownDelay = 300 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- RunServer(ctx, NewServer(cfg, handler), listener, cfg, func() { notified.Store(true) })
+	}()
+	cancel()
+
+	// during the delay the server still answers, reporting that it is shutting down
+	deadline := time.Now().Add(cfg.ShutdownDelay / 2)
+	for !notified.Load() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	resp, err := http.Get("http://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("server should keep serving during the delay: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status incorrect. got %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+
+	if err := <-runErr; err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+}
@@ -1,19 +1,26 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	blobs BlobStore
+	media MediaConfig
 }
 
-func NewProductService(db *gorm.DB) *ProductService {
-	return &ProductService{db: db}
+func NewProductService(db *gorm.DB, blobs BlobStore, media MediaConfig) *ProductService {
+	return &ProductService{db: db, blobs: blobs, media: media}
 }
 
 const (
@@ -21,31 +28,195 @@ const (
 	DefaultPageSize = 10
 )
 
-func (s *ProductService) CreateProduct(req ProductCreateRequest) (*Product, error) {
+func (s *ProductService) CreateProduct(ctx context.Context, req ProductCreateRequest) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.CreateProduct")
+	defer span.End()
+
+	product := newProduct(req)
+
+	if err := checkBundleFields(&product, req.Components != nil); err != nil {
+		return nil, err
+	}
+	if err := validateAttributes(s.db.WithContext(ctx), product.Category, product.Attributes); err != nil {
+		return nil, err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(&product).Error; err != nil {
+			return err
+		}
+		if product.Type == ProductTypeBundle {
+			return replaceBundleComponents(tx, &product, req.Components)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, productConflictError(err, product)
+	}
+
+	if err := resolveBundle(s.db.WithContext(ctx), &product, 0); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// newProduct builds a product from req, filling in the defaults for omitted fields.
+func newProduct(req ProductCreateRequest) Product {
 	product := Product{
 		Name:        req.Name,
 		Description: req.Description,
 		SKU:         req.SKU,
+		Barcode:     NormalizeGTIN(req.Barcode),
 		Price:       req.Price,
 		Quantity:    req.Quantity,
 		Category:    req.Category,
+		Attributes:  JSONMap(req.Attributes),
+		Tags:        NormalizeTags(req.Tags),
+		Status:      req.Status,
+		Type:        req.Type,
+		Pricing:     req.Pricing,
+	}
+	if product.Status == "" {
+		product.Status = StatusActive
+	}
+	if product.Type == "" {
+		product.Type = ProductTypeSimple
+	}
+	if product.Pricing == "" {
+		product.Pricing = PricingFixed
+	}
+	if product.Type == ProductTypeBundle {
+		// a bundle's stock is derived from its components
+		product.Quantity = 0
+	}
+	if product.Attributes == nil {
+		product.Attributes = JSONMap{}
+	}
+	return product
+}
+
+// upsertProductSQL inserts a product or, if a live product already has its SKU, replaces that
+// product's fields. The conflict target is the partial unique index idx_sku_not_deleted, so
+// concurrent upserts of the same SKU serialise on the index instead of failing. Status and
+// created_at/created_by are kept on update, and no row is returned if the update would change
+// the type. The statement is raw SQL, so the caller passes the actor itself.
+const upsertProductSQL = `INSERT INTO products
+	(name, description, sku, barcode, price, quantity, category, status, type, pricing, attributes, tags, created_at, created_by, updated_at, updated_by)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?::jsonb, ?::jsonb, ?, ?, ?, ?)
+	ON CONFLICT (sku) WHERE deleted_at IS NULL DO UPDATE SET
+		name = EXCLUDED.name,
+		description = EXCLUDED.description,
+		barcode = EXCLUDED.barcode,
+		price = EXCLUDED.price,
+		quantity = EXCLUDED.quantity,
+		category = EXCLUDED.category,
+		pricing = EXCLUDED.pricing,
+		attributes = EXCLUDED.attributes,
+		tags = EXCLUDED.tags,
+		updated_at = EXCLUDED.updated_at,
+		updated_by = EXCLUDED.updated_by
+	WHERE products.type = EXCLUDED.type
+	RETURNING id, (xmax = 0) AS inserted`
+
+// UpsertProductBySKU creates the product with the given SKU if no live product has it and
+// fully replaces the existing product otherwise, reporting whether it was created. An existing
+// product keeps its lifecycle status, which is changed through the transition endpoints, and
+// its type.
+func (s *ProductService) UpsertProductBySKU(ctx context.Context, sku string, req ProductCreateRequest) (*Product, bool, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.UpsertProductBySKU")
+	defer span.End()
+
+	req.SKU = sku
+	product := newProduct(req)
+
+	if err := checkBundleFields(&product, req.Components != nil); err != nil {
+		return nil, false, err
+	}
+	if err := validateAttributes(s.db.WithContext(ctx), product.Category, product.Attributes); err != nil {
+		return nil, false, err
 	}
 
-	err := s.db.Create(&product).Error
+	var result struct {
+		ID       uint
+		Inserted bool
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		actor, _ := actorFromContext(ctx)
+		err := tx.Raw(upsertProductSQL,
+			product.Name, product.Description, product.SKU, product.Barcode, product.Price, product.Quantity,
+			product.Category, product.Status, product.Type, product.Pricing, product.Attributes, product.Tags,
+			now, actor, now, actor,
+		).Scan(&result).Error
+		if err != nil {
+			return err
+		}
+		if result.ID == 0 {
+			return fmt.Errorf("%w: the type of product %s can't be changed", ErrInvalidBundle, sku)
+		}
+
+		product.ID = result.ID
+		if product.Type == ProductTypeBundle {
+			if err := replaceBundleComponents(tx, &product, req.Components); err != nil {
+				return err
+			}
+		}
+		return refreshBundlePrices(tx, product.ID)
+	})
+	if err != nil {
+		return nil, false, productConflictError(err, product)
+	}
+
+	upserted, err := s.GetProduct(ctx, int(result.ID))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return upserted, result.Inserted, nil
+}
+
+func (s *ProductService) GetProduct(ctx context.Context, id int) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProduct")
+	defer span.End()
+
+	return s.GetProductWithOptions(ctx, id, ProductReadOptions{})
+}
+
+// GetProductWithOptions loads a product with the relations in opts.Expand, reading only the
+// columns needed for opts.Fields and skipping the margin unless it is requested.
+func (s *ProductService) GetProductWithOptions(ctx context.Context, id int, opts ProductReadOptions) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProductWithOptions")
+	defer span.End()
+
+	var product Product
+
+	err := opts.Expand.Apply(selectFields(s.db.WithContext(ctx), opts.Fields)).Where("id = ?", id).First(&product).Error
 	if err != nil {
-		if isUniqueConstraintError(err) {
-			return nil, fmt.Errorf("%w: %s", ErrDuplicateSKU, req.SKU)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
 		}
 		return nil, err
 	}
 
+	if err := resolveBundle(s.db.WithContext(ctx), &product, 0); err != nil {
+		return nil, err
+	}
+	if opts.Fields.Has("margin") {
+		if err := loadProductMargin(s.db.WithContext(ctx), &product); err != nil {
+			return nil, err
+		}
+	}
+
 	return &product, nil
 }
 
-func (s *ProductService) GetProduct(id int) (*Product, error) {
+// findProduct loads a product row as stored, without deriving bundle stock and price.
+func (s *ProductService) findProduct(ctx context.Context, id int) (*Product, error) {
 	var product Product
 
-	err := s.db.Where("id = ?", id).First(&product).Error
+	err := s.db.WithContext(ctx).Where("id = ?", id).First(&product).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrNotFound
@@ -56,18 +227,122 @@ func (s *ProductService) GetProduct(id int) (*Product, error) {
 	return &product, nil
 }
 
-func (s *ProductService) GetProducts(requestedPage, requestedSize *int) (*BulkProductResponse, error) {
+// GetProductByBarcode looks up a live product by GTIN. UPC-A codes match the GTIN-13 they
+// were normalised to.
+func (s *ProductService) GetProductByBarcode(ctx context.Context, code string) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProductByBarcode")
+	defer span.End()
+
+	if !IsValidGTIN(code) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidBarcode, code)
+	}
+
+	var product Product
+
+	err := s.db.WithContext(ctx).Where("barcode = ?", NormalizeGTIN(code)).First(&product).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := resolveBundle(s.db.WithContext(ctx), &product, 0); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// GetProductIDBySKU returns the id of the non-deleted product with the given SKU.
+func (s *ProductService) GetProductIDBySKU(ctx context.Context, sku string) (int, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProductIDBySKU")
+	defer span.End()
+
+	var product Product
+
+	err := s.db.WithContext(ctx).Select("id").Where("sku = ?", sku).First(&product).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	return int(product.ID), nil
+}
+
+// LookupProducts fetches the non-deleted products matching any of the requested SKUs or ids in
+// a single query. A product matched by both its SKU and its id is only returned once.
+func (s *ProductService) LookupProducts(ctx context.Context, req ProductLookupRequest) (*ProductLookupResponse, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.LookupProducts")
+	defer span.End()
+
+	products := []Product{}
+
+	var conditions []string
+	var args []interface{}
+	if len(req.SKUs) > 0 {
+		conditions = append(conditions, "sku IN ?")
+		args = append(args, req.SKUs)
+	}
+	if len(req.IDs) > 0 {
+		conditions = append(conditions, "id IN ?")
+		args = append(args, req.IDs)
+	}
+	if len(conditions) == 0 {
+		return &ProductLookupResponse{Products: products, MissingSKUs: []string{}, MissingIDs: []int{}}, nil
+	}
+
+	err := s.db.WithContext(ctx).Where("("+strings.Join(conditions, " OR ")+")", args...).Order("id ASC").Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	foundSKUs := make(map[string]bool, len(products))
+	foundIDs := make(map[int]bool, len(products))
+	for i := range products {
+		foundSKUs[products[i].SKU] = true
+		foundIDs[int(products[i].ID)] = true
+	}
+	if err := resolveBundles(s.db.WithContext(ctx), productPointers(products), 0); err != nil {
+		return nil, err
+	}
+
+	response := ProductLookupResponse{
+		Products:    products,
+		MissingSKUs: []string{},
+		MissingIDs:  []int{},
+	}
+	for _, sku := range req.SKUs {
+		if !foundSKUs[sku] && !containsString(response.MissingSKUs, sku) {
+			response.MissingSKUs = append(response.MissingSKUs, sku)
+		}
+	}
+	for _, id := range req.IDs {
+		if !foundIDs[id] && !containsInt(response.MissingIDs, id) {
+			response.MissingIDs = append(response.MissingIDs, id)
+		}
+	}
+
+	return &response, nil
+}
+
+func (s *ProductService) GetProducts(ctx context.Context, requestedPage, requestedSize *int, filter ProductFilter, opts ProductReadOptions) (*BulkProductResponse, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProducts")
+	defer span.End()
+
 	var products []Product
 	var total int64
 
 	limit, offset, page := CalculatePagination(requestedPage, requestedSize)
 
-	err := s.db.Model(&Product{}).Count(&total).Error
+	err := applyProductFilter(s.db.WithContext(ctx).Model(&Product{}), filter).Count(&total).Error
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.db.Order("id ASC").Offset(offset).Limit(limit).Find(&products).Error
+	err = applyProductFilter(opts.Expand.Apply(selectFields(s.db.WithContext(ctx), opts.Fields)), filter).Order("id ASC").Offset(offset).Limit(limit).Find(&products).Error
 	if err != nil {
 		return nil, err
 	}
@@ -76,6 +351,10 @@ func (s *ProductService) GetProducts(requestedPage, requestedSize *int) (*BulkPr
 		return nil, ErrOutOfRange
 	}
 
+	if err := resolveBundles(s.db.WithContext(ctx), productPointers(products), 0); err != nil {
+		return nil, err
+	}
+
 	totalPages := CalculateTotalPages(total, limit)
 
 	response := BulkProductResponse{
@@ -89,8 +368,11 @@ func (s *ProductService) GetProducts(requestedPage, requestedSize *int) (*BulkPr
 	return &response, nil
 }
 
-func (s *ProductService) UpdateProduct(id int, req ProductUpdateRequest) (*Product, error) {
-	product, err := s.GetProduct(id)
+func (s *ProductService) UpdateProduct(ctx context.Context, id int, req ProductUpdateRequest) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.UpdateProduct")
+	defer span.End()
+
+	product, err := s.findProduct(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +386,9 @@ func (s *ProductService) UpdateProduct(id int, req ProductUpdateRequest) (*Produ
 	if req.SKU != nil {
 		product.SKU = *req.SKU
 	}
+	if req.Barcode != nil {
+		product.Barcode = NormalizeGTIN(*req.Barcode)
+	}
 	if req.Price != nil {
 		product.Price = *req.Price
 	}
@@ -113,26 +398,341 @@ func (s *ProductService) UpdateProduct(id int, req ProductUpdateRequest) (*Produ
 	if req.Category != nil {
 		product.Category = *req.Category
 	}
+	if req.Attributes != nil {
+		product.Attributes = JSONMap(req.Attributes)
+	}
+	if req.Tags != nil {
+		product.Tags = NormalizeTags(req.Tags)
+	}
+	if req.AddTags != nil || req.RemoveTags != nil {
+		product.Tags = ApplyTagChanges(product.Tags, req.AddTags, req.RemoveTags)
+	}
+	if req.Pricing != nil {
+		product.Pricing = *req.Pricing
+	}
 
-	err = s.db.Where("id = ?", id).Save(product).Error
-	if err != nil {
-		if isUniqueConstraintError(err) && req.SKU != nil {
-			return nil, fmt.Errorf("%w: %s", ErrDuplicateSKU, *req.SKU)
+	if err := checkBundleFields(product, req.Components != nil); err != nil {
+		return nil, err
+	}
+	if err := validateAttributes(s.db.WithContext(ctx), product.Category, product.Attributes); err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if req.Components != nil {
+			if err := replaceBundleComponents(tx, product, req.Components); err != nil {
+				return err
+			}
+		} else if product.Type == ProductTypeBundle && product.Pricing == PricingComputed {
+			if err := storeComputedPrice(tx, product); err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit(clause.Associations).Where("id = ?", id).Save(product).Error; err != nil {
+			return err
 		}
+		return refreshBundlePrices(tx, product.ID)
+	})
+	if err != nil {
+		return nil, productConflictError(err, *product)
+	}
+
+	if err := resolveBundle(s.db.WithContext(ctx), product, 0); err != nil {
 		return nil, err
 	}
 
 	return product, nil
 }
 
-func (s *ProductService) DeleteProduct(id int) error {
-	result := s.db.Delete(&Product{}, id)
+// ReplaceProduct overwrites every mutable field of a product with req, resetting omitted
+// fields to their defaults. The lifecycle status is changed through the transition endpoints
+// and the type can't be changed, so both are kept; callers reject a status in req.
+func (s *ProductService) ReplaceProduct(ctx context.Context, id int, req ProductCreateRequest) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.ReplaceProduct")
+	defer span.End()
+
+	return s.PatchProduct(ctx, id, func(ProductCreateRequest) (ProductCreateRequest, error) {
+		return req, nil
+	})
+}
+
+// PatchProduct replaces a product with the result of calling patch on its create-request form,
+// as ReplaceProduct does. The product is locked while it is patched so that concurrent patches
+// apply one after the other. Errors returned by patch are passed through.
+func (s *ProductService) PatchProduct(ctx context.Context, id int, patch func(ProductCreateRequest) (ProductCreateRequest, error)) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.PatchProduct")
+	defer span.End()
+
+	var product Product
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&existing).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := resolveBundle(tx, &existing, 0); err != nil {
+			return err
+		}
+
+		req, err := patch(productDocument(&existing))
+		if err != nil {
+			return err
+		}
+
+		product = newProduct(req)
+		if product.Type != existing.Type {
+			return fmt.Errorf("%w: the type of product %d can't be changed", ErrInvalidBundle, id)
+		}
+		product.ID = existing.ID
+		product.Status = existing.Status
+		product.CreatedAt = existing.CreatedAt
+		product.CreatedBy = existing.CreatedBy
+
+		if err := checkBundleFields(&product, req.Components != nil); err != nil {
+			return err
+		}
+		if err := validateAttributes(tx, product.Category, product.Attributes); err != nil {
+			return err
+		}
+
+		if product.Type == ProductTypeBundle {
+			if err := replaceBundleComponents(tx, &product, req.Components); err != nil {
+				return err
+			}
+		}
+		if err := tx.Omit(clause.Associations).Save(&product).Error; err != nil {
+			return err
+		}
+		return refreshBundlePrices(tx, product.ID)
+	})
+	if err != nil {
+		return nil, productConflictError(err, product)
+	}
+
+	if err := resolveBundle(s.db.WithContext(ctx), &product, 0); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// TransitionProduct moves a product to a new lifecycle status, rejecting transitions that the
+// state machine doesn't allow with a *TransitionError.
+func (s *ProductService) TransitionProduct(ctx context.Context, id int, to string) (*Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.TransitionProduct")
+	defer span.End()
+
+	var product Product
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&product).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if !CanTransition(product.Status, to) {
+			return &TransitionError{From: product.Status, To: to}
+		}
+
+		product.Status = to
+		return tx.Model(&product).Update("status", to).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolveBundle(s.db.WithContext(ctx), &product, 0); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// productStatsColumns computes ProductStats in SQL. The prices are 0 when there are no products.
+const productStatsColumns = `COUNT(*) AS count,
+	COALESCE(SUM(price * quantity), 0) AS inventory_value,
+	COALESCE(MIN(price), 0) AS min_price,
+	COALESCE(MAX(price), 0) AS max_price,
+	COALESCE(ROUND(AVG(price), 2), 0) AS avg_price,
+	COUNT(*) FILTER (WHERE quantity = 0 AND type = 'simple') AS out_of_stock`
+
+// GetProductStats aggregates the products matching filter, both overall and per category.
+func (s *ProductService) GetProductStats(ctx context.Context, filter ProductFilter) (*ProductStatsResponse, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProductStats")
+	defer span.End()
+
+	response := ProductStatsResponse{Categories: []CategoryStats{}}
+
+	err := applyProductFilter(s.db.WithContext(ctx).Model(&Product{}), filter).Select(productStatsColumns).Scan(&response.ProductStats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyProductFilter(s.db.WithContext(ctx).Model(&Product{}), filter).
+		Select("category, " + productStatsColumns).
+		Group("category").
+		Order("category ASC").
+		Scan(&response.Categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (s *ProductService) GetTags(ctx context.Context) ([]TagCount, error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetTags")
+	defer span.End()
+
+	tags := []TagCount{}
+
+	err := s.db.WithContext(ctx).Model(&Product{}).
+		Select("tag, COUNT(*) AS count").
+		Joins("CROSS JOIN LATERAL jsonb_array_elements_text(products.tags) AS tag").
+		Group("tag").
+		Order("count DESC, tag ASC").
+		Scan(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (s *ProductService) DeleteProduct(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "ProductService.DeleteProduct")
+	defer span.End()
+
+	// Locking the product keeps a bundle from taking it as a component between the check and
+	// the delete.
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var product Product
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&product).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := checkNotBundleComponent(tx, id, false); err != nil {
+			return err
+		}
+		return tx.Delete(&product).Error
+	})
+}
 
-	if result.RowsAffected == 0 {
+// lockProduct locks the product's row for the rest of the transaction, so that changes to data
+// read and rewritten as a whole, such as the ordering of its media or its preferred supplier,
+// apply one after another.
+func lockProduct(tx *gorm.DB, productID int) error {
+	var product Product
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", productID).First(&product).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return ErrNotFound
 	}
+	return err
+}
 
-	return result.Error
+// selectFields limits query to the columns needed for fields.
+func selectFields(query *gorm.DB, fields FieldSet) *gorm.DB {
+	if fields == nil {
+		return query
+	}
+	return query.Select(fields.Columns())
+}
+
+// applyProductFilter adds the WHERE clauses for filter to query. Attribute filters use jsonb
+// containment so they can be served by the GIN index on products.attributes.
+func applyProductFilter(query *gorm.DB, filter ProductFilter) *gorm.DB {
+	for name, value := range filter.Attributes {
+		typed, raw := attributeFilterDocuments(name, value)
+		if typed == raw {
+			query = query.Where("attributes @> ?::jsonb", raw)
+		} else {
+			query = query.Where("(attributes @> ?::jsonb OR attributes @> ?::jsonb)", typed, raw)
+		}
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if len(filter.TagsAll) > 0 {
+		query = query.Where("tags @> ?::jsonb", StringList(filter.TagsAll))
+	}
+	if filter.Expression != nil {
+		sql, args := filter.Expression.SQL()
+		query = query.Where(sql, args...)
+	}
+	if len(filter.TagsAny) > 0 {
+		conditions := make([]string, len(filter.TagsAny))
+		args := make([]interface{}, len(filter.TagsAny))
+		for i, tag := range filter.TagsAny {
+			conditions[i] = "tags @> ?::jsonb"
+			args[i] = StringList{tag}
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+	return query
+}
+
+// attributeFilterDocuments builds the containment documents for an attribute filter. Query
+// values are untyped, so a value that parses as a JSON number or boolean also matches the
+// string form, e.g. isbn=123 matches both {"isbn": 123} and {"isbn": "123"}.
+func attributeFilterDocuments(name, value string) (typed, raw string) {
+	rawBytes, _ := json.Marshal(map[string]string{name: value})
+	raw = string(rawBytes)
+
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		return raw, raw
+	}
+	switch parsed.(type) {
+	case float64, bool:
+		typedBytes, _ := json.Marshal(map[string]interface{}{name: parsed})
+		return string(typedBytes), raw
+	}
+	return raw, raw
+}
+
+// NormalizeTags trims and lower-cases tags and drops blanks and duplicates, keeping the
+// order in which tags first appear.
+func NormalizeTags(tags []string) StringList {
+	normalized := StringList{}
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+// ApplyTagChanges adds and then removes tags from current.
+func ApplyTagChanges(current StringList, add, remove []string) StringList {
+	removed := make(map[string]bool, len(remove))
+	for _, tag := range NormalizeTags(remove) {
+		removed[tag] = true
+	}
+
+	result := StringList{}
+	for _, tag := range NormalizeTags(append(append([]string{}, current...), add...)) {
+		if !removed[tag] {
+			result = append(result, tag)
+		}
+	}
+
+	return result
 }
 
 func CalculatePagination(page, size *int) (limit, offset, actualPage int) {
@@ -157,7 +757,29 @@ func CalculateTotalPages(total int64, limit int) int64 {
 	return (total + int64(limit) - 1) / int64(limit)
 }
 
+func containsInt(list []int, n int) bool {
+	for _, item := range list {
+		if item == n {
+			return true
+		}
+	}
+	return false
+}
+
 func isUniqueConstraintError(err error) bool {
 	var pgErr *pgconn.PgError
 	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
+
+// productConflictError maps a unique constraint violation on products to ErrDuplicateSKU or
+// ErrDuplicateBarcode depending on the index that was violated. Other errors are returned as is.
+func productConflictError(err error, product Product) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return err
+	}
+	if pgErr.ConstraintName == "idx_barcode_not_deleted" {
+		return fmt.Errorf("%w: %s", ErrDuplicateBarcode, product.Barcode)
+	}
+	return fmt.Errorf("%w: %s", ErrDuplicateSKU, product.SKU)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestProductsBySKU(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	for _, req := range getSampleProductRequests() {
+		e.POST("/api/v1/products").WithJSON(req).Expect().Status(http.StatusCreated)
+	}
+
+	e.GET("/api/v1/products/sku/5678").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("id").Number().IsEqual(2)
+	e.GET("/api/v1/products/sku/unknown").Expect().Status(http.StatusNotFound)
+
+	e.PATCH("/api/v1/products/sku/5678").WithJSON(ProductUpdateRequest{Quantity: intPtr(42)}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("quantity").Number().IsEqual(42)
+	e.PATCH("/api/v1/products/sku/unknown").WithJSON(ProductUpdateRequest{Quantity: intPtr(42)}).
+		Expect().
+		Status(http.StatusNotFound)
+
+	// renaming the SKU moves the product to its new path
+	e.PATCH("/api/v1/products/sku/5678").WithJSON(ProductUpdateRequest{SKU: strPtr("5678-B")}).
+		Expect().
+		Status(http.StatusOK)
+	e.GET("/api/v1/products/sku/5678").Expect().Status(http.StatusNotFound)
+	e.GET("/api/v1/products/sku/5678-B").Expect().Status(http.StatusOK)
+
+	e.DELETE("/api/v1/products/sku/5678-B").Expect().Status(http.StatusNoContent)
+	e.GET("/api/v1/products/sku/5678-B").Expect().Status(http.StatusNotFound)
+	e.DELETE("/api/v1/products/sku/5678-B").Expect().Status(http.StatusNotFound)
+
+	t.Run("Batch lookup", func(t *testing.T) {
+		response := e.POST("/api/v1/products/lookup").
+			WithJSON(ProductLookupRequest{SKUs: []string{"91011", "1234", "5678-B", "nope", "nope"}, IDs: []int{1, 2, 1000000}}).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object()
+
+		// product 1 matches both its SKU and its id but is only returned once
+		products := response.Value("products").Array()
+		products.Length().IsEqual(2)
+		products.Value(0).Object().Value("id").Number().IsEqual(1)
+		products.Value(1).Object().Value("id").Number().IsEqual(3)
+
+		response.Value("missing_skus").Array().IsEqual([]string{"5678-B", "nope"})
+		response.Value("missing_ids").Array().IsEqual([]int{2, 1000000})
+	})
+
+	t.Run("Invalid batch lookups", func(t *testing.T) {
+		tooMany := make([]string, 101)
+		for i := range tooMany {
+			tooMany[i] = "sku"
+		}
+
+		testCases := []struct {
+			name    string
+			request ProductLookupRequest
+		}{
+			{"Empty lookup", ProductLookupRequest{}},
+			{"Too many SKUs", ProductLookupRequest{SKUs: tooMany}},
+			{"Blank SKU", ProductLookupRequest{SKUs: []string{""}}},
+			{"Invalid ID", ProductLookupRequest{IDs: []int{0}}},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				e.POST("/api/v1/products/lookup").WithJSON(tc.request).
+					Expect().
+					Status(http.StatusBadRequest)
+			})
+		}
+	})
+}
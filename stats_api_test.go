@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestProductStats(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	// empty catalog
+	empty := e.GET("/api/v1/products/stats").Expect().Status(http.StatusOK).JSON().Object()
+	empty.Value("count").Number().IsEqual(0)
+	empty.Value("avg_price").Number().IsEqual(0)
+	empty.Value("categories").Array().IsEmpty()
+
+	requests := append(getSampleProductRequests(), ProductCreateRequest{
+		Name:     "sold out",
+		SKU:      "sold-out",
+		Price:    5,
+		Quantity: 0,
+		Category: "product > subtype",
+	})
+	for _, req := range requests {
+		e.POST("/api/v1/products").WithJSON(req).Expect().Status(http.StatusCreated)
+	}
+
+	stats := e.GET("/api/v1/products/stats").Expect().Status(http.StatusOK).JSON().Object()
+	stats.Value("count").Number().IsEqual(4)
+	stats.Value("inventory_value").Number().InDelta(2198.89, 0.001)
+	stats.Value("min_price").Number().InDelta(5, 0.001)
+	stats.Value("max_price").Number().InDelta(99.99, 0.001)
+	stats.Value("avg_price").Number().InDelta(33.74, 0.001)
+	stats.Value("out_of_stock").Number().IsEqual(1)
+
+	categories := stats.Value("categories").Array()
+	categories.Length().IsEqual(3)
+	subtype := categories.Value(1).Object()
+	subtype.Value("category").String().IsEqual("product > subtype")
+	subtype.Value("count").Number().IsEqual(2)
+	subtype.Value("inventory_value").Number().InDelta(99.99, 0.001)
+	subtype.Value("avg_price").Number().InDelta(52.50, 0.001)
+	subtype.Value("out_of_stock").Number().IsEqual(1)
+
+	// stats accept the list filters
+	e.GET("/api/v1/products/stats").WithQuery("filter", "price gt 10").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("count").Number().IsEqual(2)
+
+	e.POST("/api/v1/products/3/discontinue").Expect().Status(http.StatusOK)
+	e.GET("/api/v1/products/stats").Expect().Status(http.StatusOK).JSON().Object().Value("count").Number().IsEqual(3)
+	e.GET("/api/v1/products/stats").WithQuery("status", "all").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("count").Number().IsEqual(4)
+
+	e.GET("/api/v1/products/stats").WithQuery("filter", "price gt").Expect().Status(http.StatusBadRequest)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+type SupplierHandler struct {
+	supplierService *SupplierService
+	validator       *validator.Validate
+}
+
+func NewSupplierHandler(service *SupplierService, validator *validator.Validate) *SupplierHandler {
+	return &SupplierHandler{supplierService: service, validator: validator}
+}
+
+func (h *SupplierHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Create supplier")
+
+	var request SupplierCreateRequest
+	if !decodeAndValidate(w, r, h.validator, &request, "create supplier") {
+		return
+	}
+
+	supplier, err := h.supplierService.CreateSupplier(r.Context(), request)
+	if err != nil {
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to create supplier", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Supplier created successfully", zap.Uint("supplier ID", supplier.ID))
+	httpCreated(w, supplier)
+}
+
+func (h *SupplierHandler) GetSuppliers(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get suppliers", zap.String("path", r.URL.Path))
+
+	suppliers, err := h.supplierService.GetSuppliers(r.Context())
+	if err != nil {
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error("Failed to get suppliers", zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+		return
+	}
+
+	httpOK(w, suppliers)
+}
+
+func (h *SupplierHandler) GetSupplier(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get supplier", zap.String("path", r.URL.Path))
+
+	id, ok := parsePathID(w, r, "id", "invalid supplier ID")
+	if !ok {
+		return
+	}
+
+	supplier, err := h.supplierService.GetSupplier(r.Context(), id)
+	if err != nil {
+		handleSupplierError(w, r, "Failed to get supplier", err)
+		return
+	}
+
+	httpOK(w, supplier)
+}
+
+func (h *SupplierHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Update supplier", zap.String("path", r.URL.Path))
+
+	id, ok := parsePathID(w, r, "id", "invalid supplier ID")
+	if !ok {
+		return
+	}
+
+	var request SupplierUpdateRequest
+	if !decodeAndValidate(w, r, h.validator, &request, "update supplier") {
+		return
+	}
+
+	supplier, err := h.supplierService.UpdateSupplier(r.Context(), id, request)
+	if err != nil {
+		handleSupplierError(w, r, "Failed to update supplier", err)
+		return
+	}
+
+	logger.Info("Supplier updated successfully", zap.Uint("supplier ID", supplier.ID))
+	httpOK(w, supplier)
+}
+
+func (h *SupplierHandler) DeleteSupplier(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Delete supplier", zap.String("path", r.URL.Path))
+
+	id, ok := parsePathID(w, r, "id", "invalid supplier ID")
+	if !ok {
+		return
+	}
+
+	err := h.supplierService.DeleteSupplier(r.Context(), id)
+	if err != nil {
+		handleSupplierError(w, r, "Failed to delete supplier", err)
+		return
+	}
+
+	logger.Info("Supplier deleted successfully", zap.Int("supplier ID", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SupplierHandler) GetProductSuppliers(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Get product suppliers", zap.String("path", r.URL.Path))
+
+	productID, ok := parsePathID(w, r, "id", "invalid product ID")
+	if !ok {
+		return
+	}
+
+	links, err := h.supplierService.GetProductSuppliers(r.Context(), productID)
+	if err != nil {
+		handleSupplierError(w, r, "Failed to get product suppliers", err)
+		return
+	}
+
+	httpOK(w, links)
+}
+
+func (h *SupplierHandler) SetProductSupplier(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Set product supplier", zap.String("path", r.URL.Path))
+
+	productID, ok := parsePathID(w, r, "id", "invalid product ID")
+	if !ok {
+		return
+	}
+	supplierID, ok := parsePathID(w, r, "supplierId", "invalid supplier ID")
+	if !ok {
+		return
+	}
+
+	var request ProductSupplierRequest
+	if !decodeAndValidate(w, r, h.validator, &request, "set product supplier") {
+		return
+	}
+
+	link, created, err := h.supplierService.SetProductSupplier(r.Context(), productID, supplierID, request)
+	if err != nil {
+		handleSupplierError(w, r, "Failed to set product supplier", err)
+		return
+	}
+
+	logger.Info("Product supplier set successfully", zap.Int("product ID", productID), zap.Int("supplier ID", supplierID))
+	if created {
+		httpCreated(w, link)
+		return
+	}
+	httpOK(w, link)
+}
+
+func (h *SupplierHandler) DeleteProductSupplier(w http.ResponseWriter, r *http.Request) {
+	logger := LoggerFromContext(r.Context())
+	logger.Info("Delete product supplier", zap.String("path", r.URL.Path))
+
+	productID, ok := parsePathID(w, r, "id", "invalid product ID")
+	if !ok {
+		return
+	}
+	supplierID, ok := parsePathID(w, r, "supplierId", "invalid supplier ID")
+	if !ok {
+		return
+	}
+
+	err := h.supplierService.DeleteProductSupplier(r.Context(), productID, supplierID)
+	if err != nil {
+		handleSupplierError(w, r, "Failed to delete product supplier", err)
+		return
+	}
+
+	logger.Info("Product supplier deleted successfully", zap.Int("product ID", productID), zap.Int("supplier ID", supplierID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleSupplierError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	logger := LoggerFromContext(r.Context())
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrSupplierNotFound), errors.Is(err, ErrProductSupplierNotFound):
+		logger.Info(msg, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		if writeContextError(w, r, err) {
+			return
+		}
+		logger.Error(msg, zap.Error(err))
+		http.Error(w, "unexpected error occurred", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Supplier struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	Name      string         `gorm:"type:text;not null" json:"name"`
+	Email     string         `gorm:"type:text" json:"email"`
+	Phone     string         `gorm:"type:varchar(64)" json:"phone"`
+	Notes     string         `gorm:"type:text" json:"notes"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+type SupplierCreateRequest struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email,omitempty" validate:"omitempty,email"`
+	Phone string `json:"phone,omitempty" validate:"max=64"`
+	Notes string `json:"notes,omitempty"`
+}
+
+type SupplierUpdateRequest struct {
+	Name  *string `json:"name,omitempty" validate:"omitempty,min=1"`
+	Email *string `json:"email,omitempty" validate:"omitempty,email"`
+	Phone *string `json:"phone,omitempty" validate:"omitempty,max=64"`
+	Notes *string `json:"notes,omitempty"`
+}
+
+// ProductSupplier links a product to a supplier with the supplier's terms for it. At most one
+// supplier per product is preferred.
+type ProductSupplier struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	ProductID    uint      `gorm:"not null;uniqueIndex:idx_product_supplier" json:"product_id"`
+	SupplierID   uint      `gorm:"not null;uniqueIndex:idx_product_supplier;index" json:"supplier_id"`
+	Supplier     *Supplier `gorm:"constraint:OnDelete:CASCADE" json:"supplier,omitempty"`
+	SupplierSKU  string    `gorm:"type:varchar(128)" json:"supplier_sku"`
+	CostPrice    float64   `gorm:"type:decimal(10,2);not null" json:"cost_price"`
+	LeadTimeDays int       `gorm:"not null" json:"lead_time_days"`
+	Preferred    bool      `gorm:"not null;default:false" json:"preferred"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type ProductSupplierRequest struct {
+	SupplierSKU  string  `json:"supplier_sku,omitempty" validate:"max=128"`
+	CostPrice    float64 `json:"cost_price" validate:"gte=0"`
+	LeadTimeDays int     `json:"lead_time_days" validate:"min=0"`
+	Preferred    bool    `json:"preferred,omitempty"`
+}
+
+// ProductMargin compares a product's price with what it costs from a supplier.
+type ProductMargin struct {
+	SupplierID    uint    `json:"supplier_id"`
+	CostPrice     float64 `json:"cost_price"`
+	Margin        float64 `json:"margin"`
+	MarginPercent float64 `json:"margin_percent"`
+}
+
+// CalculateMargin returns the margin of selling at price something bought at cost. The
+// percentage is relative to the price, and zero when the price is zero.
+func CalculateMargin(price, cost float64) (margin, percent float64) {
+	margin = math.Round((price-cost)*100) / 100
+	if price == 0 {
+		return margin, 0
+	}
+	return margin, math.Round((price-cost)/price*10000) / 100
+}
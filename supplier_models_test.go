@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestCalculateMargin(t *testing.T) {
+	var tests = []struct {
+		name    string
+		price   float64
+		cost    float64
+		margin  float64
+		percent float64
+	}{
+		{"simple margin", 100, 60, 40, 40},
+		{"rounded", 9.99, 3.33, 6.66, 66.67},
+		{"no margin", 10, 10, 0, 0},
+		{"negative margin", 10, 12.5, -2.5, -25},
+		{"zero price", 0, 5, -5, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			margin, percent := CalculateMargin(tt.price, tt.cost)
+			if margin != tt.margin {
+				t.Errorf("margin incorrect. got %v, want %v", margin, tt.margin)
+			}
+			if percent != tt.percent {
+				t.Errorf("percent incorrect. got %v, want %v", percent, tt.percent)
+			}
+		})
+	}
+}
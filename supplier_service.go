@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SupplierService struct {
+	db *gorm.DB
+}
+
+func NewSupplierService(db *gorm.DB) *SupplierService {
+	return &SupplierService{db: db}
+}
+
+func (s *SupplierService) CreateSupplier(ctx context.Context, req SupplierCreateRequest) (*Supplier, error) {
+	ctx, span := tracer.Start(ctx, "SupplierService.CreateSupplier")
+	defer span.End()
+
+	supplier := Supplier{
+		Name:  req.Name,
+		Email: req.Email,
+		Phone: req.Phone,
+		Notes: req.Notes,
+	}
+
+	err := s.db.WithContext(ctx).Create(&supplier).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &supplier, nil
+}
+
+func (s *SupplierService) GetSupplier(ctx context.Context, id int) (*Supplier, error) {
+	ctx, span := tracer.Start(ctx, "SupplierService.GetSupplier")
+	defer span.End()
+
+	var supplier Supplier
+
+	err := s.db.WithContext(ctx).Where("id = ?", id).First(&supplier).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSupplierNotFound
+		}
+		return nil, err
+	}
+
+	return &supplier, nil
+}
+
+func (s *SupplierService) GetSuppliers(ctx context.Context) ([]Supplier, error) {
+	ctx, span := tracer.Start(ctx, "SupplierService.GetSuppliers")
+	defer span.End()
+
+	suppliers := []Supplier{}
+
+	err := s.db.WithContext(ctx).Order("id ASC").Find(&suppliers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return suppliers, nil
+}
+
+func (s *SupplierService) UpdateSupplier(ctx context.Context, id int, req SupplierUpdateRequest) (*Supplier, error) {
+	ctx, span := tracer.Start(ctx, "SupplierService.UpdateSupplier")
+	defer span.End()
+
+	supplier, err := s.GetSupplier(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		supplier.Name = *req.Name
+	}
+	if req.Email != nil {
+		supplier.Email = *req.Email
+	}
+	if req.Phone != nil {
+		supplier.Phone = *req.Phone
+	}
+	if req.Notes != nil {
+		supplier.Notes = *req.Notes
+	}
+
+	err = s.db.WithContext(ctx).Where("id = ?", id).Save(supplier).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return supplier, nil
+}
+
+// DeleteSupplier soft-deletes a supplier and removes its links to products.
+func (s *SupplierService) DeleteSupplier(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "SupplierService.DeleteSupplier")
+	defer span.End()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Supplier{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSupplierNotFound
+		}
+
+		return tx.Where("supplier_id = ?", id).Delete(&ProductSupplier{}).Error
+	})
+}
+
+func (s *SupplierService) GetProductSuppliers(ctx context.Context, productID int) ([]ProductSupplier, error) {
+	ctx, span := tracer.Start(ctx, "SupplierService.GetProductSuppliers")
+	defer span.End()
+
+	if err := s.checkProductExists(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	links := []ProductSupplier{}
+
+	err := s.db.WithContext(ctx).Where("product_id = ?", productID).Preload("Supplier").Order("preferred DESC, cost_price ASC, supplier_id ASC").Find(&links).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// SetProductSupplier creates or replaces the link between a product and a supplier. Marking a
+// supplier as preferred clears the flag on the product's other suppliers; the product is locked
+// meanwhile so that concurrent calls apply one after the other.
+func (s *SupplierService) SetProductSupplier(ctx context.Context, productID, supplierID int, req ProductSupplierRequest) (*ProductSupplier, bool, error) {
+	ctx, span := tracer.Start(ctx, "SupplierService.SetProductSupplier")
+	defer span.End()
+
+	if _, err := s.GetSupplier(ctx, supplierID); err != nil {
+		return nil, false, err
+	}
+
+	link := ProductSupplier{
+		ProductID:    uint(productID),
+		SupplierID:   uint(supplierID),
+		SupplierSKU:  req.SupplierSKU,
+		CostPrice:    req.CostPrice,
+		LeadTimeDays: req.LeadTimeDays,
+		Preferred:    req.Preferred,
+	}
+	var created bool
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Concurrent calls would each clear the old preferred supplier and then both insert one.
+		if err := lockProduct(tx, productID); err != nil {
+			return err
+		}
+
+		var count int64
+		err := tx.Model(&ProductSupplier{}).Where("product_id = ? AND supplier_id = ?", productID, supplierID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		created = count == 0
+
+		if req.Preferred {
+			err := tx.Model(&ProductSupplier{}).Where("product_id = ? AND supplier_id <> ?", productID, supplierID).Update("preferred", false).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "product_id"}, {Name: "supplier_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"supplier_sku", "cost_price", "lead_time_days", "preferred", "updated_at"}),
+		}).Create(&link).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("product_id = ? AND supplier_id = ?", productID, supplierID).Preload("Supplier").First(&link).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &link, created, nil
+}
+
+func (s *SupplierService) DeleteProductSupplier(ctx context.Context, productID, supplierID int) error {
+	ctx, span := tracer.Start(ctx, "SupplierService.DeleteProductSupplier")
+	defer span.End()
+
+	result := s.db.WithContext(ctx).Where("product_id = ? AND supplier_id = ?", productID, supplierID).Delete(&ProductSupplier{})
+
+	if result.RowsAffected == 0 {
+		return ErrProductSupplierNotFound
+	}
+
+	return result.Error
+}
+
+func (s *SupplierService) checkProductExists(ctx context.Context, productID int) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&Product{}).Where("id = ?", productID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// loadProductMargin sets product.Margin from the preferred supplier, or from the cheapest one
+// if none is preferred. Products without suppliers are left without a margin.
+func loadProductMargin(db *gorm.DB, product *Product) error {
+	var link ProductSupplier
+
+	err := db.Where("product_id = ?", product.ID).Order("preferred DESC, cost_price ASC, supplier_id ASC").First(&link).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	margin, percent := CalculateMargin(product.Price, link.CostPrice)
+	product.Margin = &ProductMargin{
+		SupplierID:    link.SupplierID,
+		CostPrice:     link.CostPrice,
+		Margin:        margin,
+		MarginPercent: percent,
+	}
+	return nil
+}
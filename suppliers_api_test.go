@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestSuppliers(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	testCases := []struct {
+		name           string
+		supplier       SupplierCreateRequest
+		expectedStatus int
+	}{
+		{"Valid supplier", SupplierCreateRequest{Name: "Acme", Email: "sales@acme.test"}, http.StatusCreated},
+		{"Second valid supplier", SupplierCreateRequest{Name: "Globex"}, http.StatusCreated},
+		{"Missing name", SupplierCreateRequest{Email: "sales@initech.test"}, http.StatusBadRequest},
+		{"Invalid email", SupplierCreateRequest{Name: "Initech", Email: "not an email"}, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.POST("/api/v1/suppliers").WithJSON(tc.supplier).
+				Expect().
+				Status(tc.expectedStatus)
+		})
+	}
+
+	e.GET("/api/v1/suppliers").Expect().Status(http.StatusOK).JSON().Array().Length().IsEqual(2)
+	e.PATCH("/api/v1/suppliers/2").WithJSON(SupplierUpdateRequest{Phone: strPtr("555-0100")}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("phone").String().IsEqual("555-0100")
+	e.GET("/api/v1/suppliers/1000000").Expect().Status(http.StatusNotFound)
+
+	// product 1 costs 99.99
+	e.POST("/api/v1/products").WithJSON(getSampleProductRequests()[0]).
+		Expect().
+		Status(http.StatusCreated)
+
+	// no suppliers, no margin
+	e.GET("/api/v1/products/1").Expect().Status(http.StatusOK).JSON().Object().NotContainsKey("margin")
+
+	e.PUT("/api/v1/products/1/suppliers/1").
+		WithJSON(ProductSupplierRequest{SupplierSKU: "ACME-1", CostPrice: 60, LeadTimeDays: 7}).
+		Expect().
+		Status(http.StatusCreated)
+	e.PUT("/api/v1/products/1/suppliers/2").
+		WithJSON(ProductSupplierRequest{SupplierSKU: "GLX-1", CostPrice: 50, LeadTimeDays: 21}).
+		Expect().
+		Status(http.StatusCreated)
+	e.PUT("/api/v1/products/1/suppliers/1000000").
+		WithJSON(ProductSupplierRequest{CostPrice: 1}).
+		Expect().
+		Status(http.StatusNotFound)
+	e.PUT("/api/v1/products/1/suppliers/1").
+		WithJSON(ProductSupplierRequest{CostPrice: -1}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	// without a preferred supplier, the cheapest one is used for the margin
+	margin := e.GET("/api/v1/products/1").Expect().Status(http.StatusOK).JSON().Object().Value("margin").Object()
+	margin.Value("supplier_id").Number().IsEqual(2)
+	margin.Value("margin").Number().IsEqual(49.99)
+
+	// updating a link returns 200 and marking it preferred switches the margin basis
+	e.PUT("/api/v1/products/1/suppliers/1").
+		WithJSON(ProductSupplierRequest{SupplierSKU: "ACME-1", CostPrice: 60, LeadTimeDays: 5, Preferred: true}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("lead_time_days").Number().IsEqual(5)
+
+	margin = e.GET("/api/v1/products/1").Expect().Status(http.StatusOK).JSON().Object().Value("margin").Object()
+	margin.Value("supplier_id").Number().IsEqual(1)
+	margin.Value("cost_price").Number().IsEqual(60)
+	margin.Value("margin").Number().IsEqual(39.99)
+	margin.Value("margin_percent").Number().IsEqual(39.99)
+
+	// only one supplier can be preferred at a time
+	e.PUT("/api/v1/products/1/suppliers/2").
+		WithJSON(ProductSupplierRequest{CostPrice: 50, Preferred: true}).
+		Expect().
+		Status(http.StatusOK)
+	links := e.GET("/api/v1/products/1/suppliers").Expect().Status(http.StatusOK).JSON().Array()
+	links.Length().IsEqual(2)
+	links.Value(0).Object().Value("supplier_id").Number().IsEqual(2)
+	links.Value(0).Object().Value("preferred").Boolean().IsTrue()
+	links.Value(1).Object().Value("preferred").Boolean().IsFalse()
+
+	// deleting a supplier removes its links
+	e.DELETE("/api/v1/suppliers/2").Expect().Status(http.StatusNoContent)
+	e.GET("/api/v1/products/1/suppliers").Expect().Status(http.StatusOK).JSON().Array().Length().IsEqual(1)
+
+	e.DELETE("/api/v1/products/1/suppliers/1").Expect().Status(http.StatusNoContent)
+	e.DELETE("/api/v1/products/1/suppliers/1").Expect().Status(http.StatusNotFound)
+}
+
+func TestConcurrentPreferredSuppliers(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	e.POST("/api/v1/products").WithJSON(getSampleProductRequests()[0]).Expect().Status(http.StatusCreated)
+	const suppliers = 6
+	for i := 0; i < suppliers; i++ {
+		e.POST("/api/v1/suppliers").WithJSON(SupplierCreateRequest{Name: "Supplier " + strconv.Itoa(i)}).
+			Expect().Status(http.StatusCreated)
+	}
+
+	// every supplier is linked as preferred at once, and the calls apply one after another
+	var wg sync.WaitGroup
+	for i := 1; i <= suppliers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			e.PUT("/api/v1/products/1/suppliers/{id}", id).
+				WithJSON(ProductSupplierRequest{CostPrice: 10, Preferred: true}).
+				Expect().Status(http.StatusCreated)
+		}(i)
+	}
+	wg.Wait()
+
+	links := e.GET("/api/v1/products/1/suppliers").Expect().Status(http.StatusOK).JSON().Array()
+	links.Length().IsEqual(suppliers)
+	preferred := 0
+	for i := 0; i < suppliers; i++ {
+		if links.Value(i).Object().Value("preferred").Boolean().Raw() {
+			preferred++
+		}
+	}
+	if preferred != 1 {
+		t.Errorf("expected one preferred supplier. got %d", preferred)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestProductTags(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	products := []ProductCreateRequest{
+		{Name: "lamp", SKU: "t1", Price: 10, Tags: []string{"Eco", "new", "eco"}},
+		{Name: "chair", SKU: "t2", Price: 10, Tags: []string{"clearance"}},
+		{Name: "desk", SKU: "t3", Price: 10, Tags: []string{"eco", "clearance"}},
+		{Name: "rug", SKU: "t4", Price: 10},
+	}
+	for _, product := range products {
+		e.POST("/api/v1/products").WithJSON(product).
+			Expect().
+			Status(http.StatusCreated)
+	}
+
+	lamp := e.GET("/api/v1/products/1").Expect().Status(http.StatusOK).JSON().Object()
+	lamp.Value("tags").Array().IsEqual([]string{"eco", "new"})
+
+	testCases := []struct {
+		name          string
+		queryParams   map[string]string
+		expectedCount int
+	}{
+		{"Any tag", map[string]string{"tags_any": "eco,clearance"}, 3},
+		{"All tags", map[string]string{"tags_all": "eco,clearance"}, 1},
+		{"Single tag", map[string]string{"tags_all": "new"}, 1},
+		{"Unknown tag", map[string]string{"tags_any": "missing"}, 0},
+		{"Any and all combined", map[string]string{"tags_any": "new,clearance", "tags_all": "eco"}, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := e.GET("/api/v1/products")
+			for key, val := range tc.queryParams {
+				request = request.WithQuery(key, val)
+			}
+			request.Expect().
+				Status(http.StatusOK).
+				JSON().Object().Value("total_count").Number().IsEqual(tc.expectedCount)
+		})
+	}
+
+	// add and remove tags on PATCH
+	e.PATCH("/api/v1/products/1").
+		WithJSON(ProductUpdateRequest{AddTags: []string{"clearance"}, RemoveTags: []string{"new"}}).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("tags").Array().IsEqual([]string{"eco", "clearance"})
+
+	cloud := e.GET("/api/v1/tags").Expect().Status(http.StatusOK).JSON().Array()
+	cloud.Length().IsEqual(2)
+	cloud.Value(0).Object().IsEqual(map[string]interface{}{"tag": "clearance", "count": 3})
+	cloud.Value(1).Object().IsEqual(map[string]interface{}{"tag": "eco", "count": 2})
+
+	// deleted products don't count towards the tag cloud
+	e.DELETE("/api/v1/products/2").Expect().Status(http.StatusNoContent)
+	e.GET("/api/v1/tags").Expect().Status(http.StatusOK).
+		JSON().Array().Value(0).Object().Value("count").Number().IsEqual(2)
+}
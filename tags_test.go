@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	var tests = []struct {
+		name string
+		tags []string
+		want StringList
+	}{
+		{"nil", nil, StringList{}},
+		{"already normal", []string{"eco", "new"}, StringList{"eco", "new"}},
+		{"case and whitespace", []string{" Eco", "NEW "}, StringList{"eco", "new"}},
+		{"duplicates and blanks", []string{"eco", "", "ECO", "  ", "new"}, StringList{"eco", "new"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeTags(tt.tags)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tags incorrect. got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTagChanges(t *testing.T) {
+	var tests = []struct {
+		name    string
+		current StringList
+		add     []string
+		remove  []string
+		want    StringList
+	}{
+		{"no changes", StringList{"eco"}, nil, nil, StringList{"eco"}},
+		{"add", StringList{"eco"}, []string{"New"}, nil, StringList{"eco", "new"}},
+		{"add existing", StringList{"eco"}, []string{"eco"}, nil, StringList{"eco"}},
+		{"remove", StringList{"eco", "new"}, nil, []string{"ECO"}, StringList{"new"}},
+		{"remove missing", StringList{"eco"}, nil, []string{"clearance"}, StringList{"eco"}},
+		{"remove wins over add", StringList{"eco"}, []string{"new"}, []string{"new"}, StringList{"eco"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ApplyTagChanges(tt.current, tt.add, tt.remove)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tags incorrect. got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
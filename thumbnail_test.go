@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGenerateThumbnail(t *testing.T) {
+	var tests = []struct {
+		name           string
+		width, height  int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{"already small", 100, 50, 100, 50},
+		{"exact size", 256, 256, 256, 256},
+		{"landscape", 1024, 512, 256, 128},
+		{"portrait", 300, 600, 128, 256},
+		{"very thin", 5000, 2, 256, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			thumbnail := GenerateThumbnail(img, ThumbnailSize)
+			if thumbnail.Bounds().Dx() != tt.expectedWidth {
+				t.Errorf("width incorrect. got %d, want %d", thumbnail.Bounds().Dx(), tt.expectedWidth)
+			}
+			if thumbnail.Bounds().Dy() != tt.expectedHeight {
+				t.Errorf("height incorrect. got %d, want %d", thumbnail.Bounds().Dy(), tt.expectedHeight)
+			}
+		})
+	}
+}
+
+func TestGenerateThumbnailAveragesPixels(t *testing.T) {
+	// alternating black and white columns average out to grey
+	img := image.NewRGBA(image.Rect(0, 0, 512, 512))
+	for x := 0; x < 512; x += 2 {
+		for y := 0; y < 512; y++ {
+			img.Set(x, y, color.White)
+			img.Set(x+1, y, color.Black)
+		}
+	}
+
+	thumbnail := GenerateThumbnail(img, ThumbnailSize)
+	r, g, b, _ := thumbnail.At(10, 10).RGBA()
+	for _, c := range []uint32{r, g, b} {
+		if c < 0x7000 || c > 0x9000 {
+			t.Fatalf("pixel not averaged. got %04x %04x %04x", r, g, b)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// RouteTimeouts sets the deadline of each request's context, so queries run through
+// db.WithContext are cancelled once the client would have given up anyway.
+type RouteTimeouts struct {
+	// Default applies to routes without an entry in Routes; zero means no deadline.
+	Default time.Duration
+	// Routes is keyed by method and path template, e.g. "GET /api/v1/products/{id}".
+	Routes map[string]time.Duration
+}
+
+func NewRouteTimeouts(cfg ServerConfig) RouteTimeouts {
+	return RouteTimeouts{Default: cfg.RequestTimeout, Routes: cfg.RouteTimeouts}
+}
+
+func (t RouteTimeouts) timeout(r *http.Request) time.Duration {
+	if d, ok := t.Routes[r.Method+" "+routeTemplate(r)]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// Middleware applies the matched route's deadline to the request context.
+func (t RouteTimeouts) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := t.timeout(r)
+		if d <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// warnUnknownRoutes logs configured timeouts that name no route of router, which are
+// otherwise silently ignored.
+func (t RouteTimeouts) warnUnknownRoutes(router *mux.Router) {
+	known := make(map[string]bool)
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			known[method+" "+routeVariablePattern.ReplaceAllString(template, "{$1}")] = true
+		}
+		return nil
+	})
+	for key := range t.Routes {
+		if !known[key] {
+			zap.L().Warn("Timeout configured for unknown route", zap.String("route", key))
+		}
+	}
+}
+
+// validRouteKey reports whether key has the "METHOD /path" form used by RouteTimeouts.
+func validRouteKey(key string) bool {
+	method, path, ok := strings.Cut(key, " ")
+	return ok && method != "" && method == strings.ToUpper(method) && strings.HasPrefix(path, "/") && !strings.Contains(path, " ")
+}
+
+// writeContextError answers a request whose context ended before its work finished: 504 when
+// the deadline passed, 503 when it was cancelled, usually because the client went away. It
+// reports whether err was such a failure.
+func writeContextError(w http.ResponseWriter, r *http.Request, err error) bool {
+	logger := LoggerFromContext(r.Context())
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(r.Context().Err(), context.DeadlineExceeded):
+		logger.Warn("Request deadline exceeded", zap.Error(err))
+		http.Error(w, "request timed out", http.StatusGatewayTimeout)
+	case errors.Is(err, context.Canceled) || errors.Is(r.Context().Err(), context.Canceled):
+		logger.Info("Request cancelled", zap.Error(err))
+		http.Error(w, "request cancelled", http.StatusServiceUnavailable)
+	default:
+		return false
+	}
+	return true
+}
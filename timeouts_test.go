@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRouteTimeouts(t *testing.T) {
+	timeouts := RouteTimeouts{
+		Default: time.Minute,
+		Routes:  map[string]time.Duration{"POST /products/{id}/media": time.Hour},
+	}
+
+	var remaining time.Duration
+	var hasDeadline bool
+	record := func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		hasDeadline, remaining = ok, time.Until(deadline)
+	}
+	router := mux.NewRouter()
+	router.Use(timeouts.Middleware)
+	router.HandleFunc("/products", record).Methods(http.MethodGet)
+	router.HandleFunc("/products/{id:[0-9]+}/media", record).Methods(http.MethodPost, http.MethodGet)
+
+	var tests = []struct {
+		name   string
+		method string
+		path   string
+		want   time.Duration
+	}{
+		{"default", http.MethodGet, "/products", time.Minute},
+		{"route override", http.MethodPost, "/products/7/media", time.Hour},
+		{"override is per method", http.MethodGet, "/products/7/media", time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+			if !hasDeadline || remaining > tt.want || remaining < tt.want-time.Second {
+				t.Errorf("deadline incorrect. got %v (set: %v), want %v", remaining, hasDeadline, tt.want)
+			}
+		})
+	}
+
+	t.Run("zero disables the deadline", func(t *testing.T) {
+		router := mux.NewRouter()
+		router.Use(RouteTimeouts{}.Middleware)
+		router.HandleFunc("/products", record)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products", nil))
+		if hasDeadline {
+			t.Errorf("expected no deadline")
+		}
+	})
+}
+
+func TestWriteContextError(t *testing.T) {
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var tests = []struct {
+		name    string
+		ctx     context.Context
+		err     error
+		handled bool
+		status  int
+	}{
+		{"wrapped deadline", context.Background(), fmt.Errorf("query failed: %w", context.DeadlineExceeded), true, http.StatusGatewayTimeout},
+		{"expired request", expired, errors.New("conn closed"), true, http.StatusGatewayTimeout},
+		{"cancelled request", cancelled, errors.New("conn closed"), true, http.StatusServiceUnavailable},
+		{"other error", context.Background(), errors.New("conn closed"), false, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(tt.ctx)
+			if handled := writeContextError(recorder, request, tt.err); handled != tt.handled {
+				t.Errorf("handled incorrect. got %v, want %v", handled, tt.handled)
+			}
+			if recorder.Code != tt.status {
+				t.Errorf("status incorrect. got %d, want %d", recorder.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestValidRouteKey(t *testing.T) {
+	var tests = []struct {
+		key  string
+		want bool
+	}{
+		{"GET /api/v1/products", true},
+		{"POST /api/v1/products/{id}/media", true},
+		{"/api/v1/products", false},
+		{"get /api/v1/products", false},
+		{"GET api/v1/products", false},
+		{"GET /api/v1/products extra", false},
+	}
+	for _, tt := range tests {
+		if got := validRouteKey(tt.key); got != tt.want {
+			t.Errorf("validRouteKey(%q) incorrect. got %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
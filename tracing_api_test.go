@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestTracingSpans(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+	e.POST("/api/v1/products").WithJSON(getSampleProductRequests()[0]).Expect().Status(http.StatusCreated)
+
+	exporter := recordSpans()
+	e.GET("/api/v1/products").Expect().Status(http.StatusOK)
+
+	spans := exporter.GetSpans()
+	request := findSpan(spans, "GET /api/v1/products")
+	service := findSpan(spans, "ProductService.GetProducts")
+	if request == nil || service == nil {
+		t.Fatalf("request or service span missing. got %d spans", len(spans))
+	}
+	if service.Parent.SpanID() != request.SpanContext.SpanID() {
+		t.Errorf("service span should be a child of the request span")
+	}
+
+	// the count and the page query are separate children of the service span
+	var queries int
+	for _, span := range spans {
+		if span.Name == "gorm.query" && span.Parent.SpanID() == service.SpanContext.SpanID() {
+			queries++
+		}
+	}
+	if queries < 2 {
+		t.Errorf("expected the count and find queries under the service span. got %d", queries)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var (
+	spanExporter     *tracetest.InMemoryExporter
+	spanExporterOnce sync.Once
+)
+
+// recordSpans installs an in-memory exporter as the global tracer provider and clears the
+// spans recorded so far. The global delegate can only be set once, so tests share it.
+func recordSpans() *tracetest.InMemoryExporter {
+	spanExporterOnce.Do(func() {
+		spanExporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	spanExporter.Reset()
+	return spanExporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	exporter := recordSpans()
+	core, logs := observer.New(zapcore.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	var handlerSpan trace.SpanContext
+	router := mux.NewRouter()
+	router.Use(Tracing, RequestLogging)
+	router.HandleFunc("/products/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		if mux.Vars(r)["id"] == "500" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("Continues an incoming trace", func(t *testing.T) {
+		exporter.Reset()
+		request := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+		request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), request)
+
+		span := findSpan(exporter.GetSpans(), "GET /products/{id}")
+		if span == nil {
+			t.Fatalf("server span missing. got %v", exporter.GetSpans())
+		}
+		if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("trace id incorrect. got %s", span.SpanContext.TraceID())
+		}
+		if span.Parent.SpanID().String() != "00f067aa0ba902b7" || !span.Parent.IsRemote() {
+			t.Errorf("parent incorrect. got %v", span.Parent)
+		}
+		if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+			t.Errorf("handler context should carry the server span")
+		}
+		if span.SpanKind != trace.SpanKindServer || span.Status.Code == codes.Error {
+			t.Errorf("kind/status incorrect. got %v %v", span.SpanKind, span.Status)
+		}
+
+		entries := logs.TakeAll()
+		if len(entries) == 0 || entries[0].ContextMap()["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("log entry should carry the trace id. got %v", entries)
+		}
+	})
+
+	t.Run("Marks server errors", func(t *testing.T) {
+		exporter.Reset()
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/products/500", nil))
+
+		span := findSpan(exporter.GetSpans(), "GET /products/{id}")
+		if span == nil {
+			t.Fatalf("server span missing")
+		}
+		if span.Parent.IsValid() {
+			t.Errorf("request without traceparent should start a new trace")
+		}
+		if span.Status.Code != codes.Error {
+			t.Errorf("status incorrect. got %v", span.Status)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func TestPutProductBySKU(t *testing.T) {
+	router, logger, db := initRouter()
+	defer logger.Sync()
+	defer CleanDatabase(db)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	e := httpexpect.Default(t, server.URL)
+
+	request := getSampleProductRequests()[0]
+	request.SKU = ""
+	request.Tags = []string{"New"}
+
+	created := e.PUT("/api/v1/products/sku/sync-1").WithJSON(request).
+		Expect().
+		Status(http.StatusCreated).
+		JSON().Object()
+	created.Value("id").Number().IsEqual(1)
+	created.Value("sku").String().IsEqual("sync-1")
+	created.Value("tags").Array().IsEqual([]string{"new"})
+
+	// lifecycle status is kept when the product is replaced
+	e.POST("/api/v1/products/1/discontinue").Expect().Status(http.StatusOK)
+
+	replacement := ProductCreateRequest{Name: "replaced", SKU: "sync-1", Price: 5, Quantity: 3}
+	replaced := e.PUT("/api/v1/products/sku/sync-1").WithJSON(replacement).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object()
+	replaced.Value("id").Number().IsEqual(1)
+	replaced.Value("name").String().IsEqual("replaced")
+	replaced.Value("description").String().IsEqual("")
+	replaced.Value("category").String().IsEqual("")
+	replaced.Value("tags").Array().IsEmpty()
+	replaced.Value("status").String().IsEqual(StatusDiscontinued)
+
+	testCases := []struct {
+		name           string
+		request        ProductCreateRequest
+		expectedStatus int
+	}{
+		{"SKU mismatch", ProductCreateRequest{Name: "x", SKU: "other", Price: 1}, http.StatusBadRequest},
+		{"Missing name", ProductCreateRequest{Price: 1}, http.StatusBadRequest},
+		{"Type change", ProductCreateRequest{Name: "x", Pricing: PricingComputed, Type: ProductTypeBundle,
+			Components: []BundleComponentRequest{{ComponentID: 1, Quantity: 1}}}, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e.PUT("/api/v1/products/sku/sync-1").WithJSON(tc.request).
+				Expect().
+				Status(tc.expectedStatus)
+		})
+	}
+
+	t.Run("Duplicate barcode", func(t *testing.T) {
+		e.PUT("/api/v1/products/sku/sync-2").WithJSON(ProductCreateRequest{Name: "x", Price: 1, Barcode: "4006381333931"}).
+			Expect().
+			Status(http.StatusCreated)
+		e.PUT("/api/v1/products/sku/sync-1").WithJSON(ProductCreateRequest{Name: "x", Price: 1, Barcode: "4006381333931"}).
+			Expect().
+			Status(http.StatusConflict)
+	})
+
+	t.Run("Deleted products are not replaced", func(t *testing.T) {
+		e.DELETE("/api/v1/products/sku/sync-1").Expect().Status(http.StatusNoContent)
+		e.PUT("/api/v1/products/sku/sync-1").WithJSON(replacement).
+			Expect().
+			Status(http.StatusCreated).
+			JSON().Object().Value("id").Number().NotEqual(1)
+	})
+
+	t.Run("Concurrent upserts", func(t *testing.T) {
+		const workers = 10
+		concurrent := ProductCreateRequest{Name: "concurrent", Price: 5}
+		statuses := make(chan int, workers)
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				statuses <- e.PUT("/api/v1/products/sku/sync-concurrent").WithJSON(concurrent).Expect().Raw().StatusCode
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+
+		counts := map[int]int{}
+		for status := range statuses {
+			counts[status]++
+		}
+		if counts[http.StatusCreated] != 1 || counts[http.StatusOK] != workers-1 {
+			t.Errorf("unexpected statuses for concurrent upserts: %v", counts)
+		}
+	})
+}